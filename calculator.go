@@ -0,0 +1,158 @@
+package solar
+
+import (
+	"sync"
+	"time"
+)
+
+// SunCalculator calculates the Sun for a fixed location. It remembers the last
+// calculated Sun, so calculating for many time instants within the same day
+// only does the date math once. This is useful for watchers that calculate the
+// temperature every second.
+//
+// A SunCalculator must not be used concurrently.
+type SunCalculator struct {
+	lat  float64
+	long float64
+
+	today     cachedSun
+	yesterday cachedSun
+	adjacent  cachedSun
+}
+
+// NewSunCalculator creates a new SunCalculator for the given latitude and
+// longitude in degrees.
+func NewSunCalculator(lat, long float64) *SunCalculator {
+	return &SunCalculator{
+		lat:  lat,
+		long: long,
+	}
+}
+
+// Sun returns the same Sun as CalculateSun for the given time instant.
+func (c *SunCalculator) Sun(t time.Time) Sun {
+	return c.today.get(t, c.lat, c.long)
+}
+
+// Temperature returns the same values as CalculateTemperature for the given
+// time instant.
+func (c *SunCalculator) Temperature(t time.Time, lo, hi Temperature) (Temperature, Sun) {
+	current := sunAt(t, c.long, c.Sun(t), func(t time.Time) Sun { return c.adjacent.get(t, c.lat, c.long) })
+	yesterdaySun := func() Sun { return c.yesterday.get(yesterday(t), c.lat, c.long) }
+	return calcTemp(t, current, yesterdaySun, lo, hi, nil), current
+}
+
+// cachedSun is a Sun cached for the day that it was calculated for.
+type cachedSun struct {
+	start time.Time // from timeTruncateDayLongitude
+	sun   Sun
+	ok    bool
+}
+
+// get returns the cached Sun if t is within its day, or it calculates and
+// caches a new one.
+func (c *cachedSun) get(t time.Time, lat, long float64) Sun {
+	start := timeTruncateDayLongitude(t, long)
+	if c.ok && c.start.Equal(start) && c.start.Location() == start.Location() {
+		return c.sun
+	}
+
+	*c = cachedSun{
+		start: start,
+		sun:   CalculateSun(t, lat, long),
+		ok:    true,
+	}
+
+	return c.sun
+}
+
+// Location is a fixed location on Earth in degrees. Unlike SunCalculator, it
+// is safe for concurrent use, since its Suns are cached in a cache shared by
+// all Locations.
+type Location struct {
+	Lat  float64
+	Long float64
+}
+
+// Sun returns the same Sun as CalculateSun for the given time instant. The
+// Sun of each day is cached, so repeated queries within the same day are only
+// a map lookup. Only the last locationCacheSize days across all Locations are
+// kept.
+func (l Location) Sun(t time.Time) Sun {
+	return locationSuns.get(t, l)
+}
+
+// locationCacheSize is the number of Suns that the Location cache keeps.
+const locationCacheSize = 64
+
+var locationSuns = newSunCache(locationCacheSize)
+
+// sunCacheKey identifies a cached Sun by its location and day.
+type sunCacheKey struct {
+	loc   Location
+	start int64          // Unix nanoseconds of timeTruncateDayLongitude
+	zone  *time.Location // of the returned times
+}
+
+// sunCache is a bounded cache of Suns that is safe for concurrent use. Once
+// full, the oldest Sun is evicted first.
+type sunCache struct {
+	mu   sync.Mutex
+	suns map[sunCacheKey]Sun
+	keys []sunCacheKey // ring buffer of the insertion order
+	next int
+}
+
+func newSunCache(size int) *sunCache {
+	return &sunCache{
+		suns: make(map[sunCacheKey]Sun, size),
+		keys: make([]sunCacheKey, 0, size),
+	}
+}
+
+// get returns the cached Sun of t's day at the given location, or it
+// calculates and caches a new one.
+func (c *sunCache) get(t time.Time, loc Location) Sun {
+	key := sunCacheKey{
+		loc:   loc,
+		start: timeTruncateDayLongitude(t, loc.Long).UnixNano(),
+		zone:  t.Location(),
+	}
+
+	c.mu.Lock()
+	sun, ok := c.suns[key]
+	c.mu.Unlock()
+
+	if ok {
+		return sun
+	}
+
+	// Calculate without holding the lock. Concurrent misses on the same day
+	// may calculate twice, but they get the same Sun.
+	sun = CalculateSun(t, loc.Lat, loc.Long)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.suns[key]; ok {
+		return sun
+	}
+
+	if len(c.keys) < cap(c.keys) {
+		c.keys = append(c.keys, key)
+	} else {
+		delete(c.suns, c.keys[c.next])
+		c.keys[c.next] = key
+		c.next = (c.next + 1) % len(c.keys)
+	}
+	c.suns[key] = sun
+
+	return sun
+}
+
+// len returns the number of cached Suns.
+func (c *sunCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.suns)
+}
@@ -0,0 +1,128 @@
+package solar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunCalculator(t *testing.T) {
+	type location struct {
+		name      string
+		lat, long float64
+	}
+
+	var locations = []location{
+		{"Los Angeles", latitude, longitude},
+		{"Tromsø", 69.65, 18.96},
+	}
+
+	start := time.Date(2021, time.May, 10, 0, 0, 0, 0, losAngeles)
+
+	for _, loc := range locations {
+		t.Run(loc.name, func(t *testing.T) {
+			c := NewSunCalculator(loc.lat, loc.long)
+
+			// Go through a few days in 10 minute steps, including when the
+			// midnight sun starts in Tromsø.
+			for ts := start; ts.Before(start.AddDate(0, 0, 14)); ts = ts.Add(10 * time.Minute) {
+				expectTemp, expectSun := CalculateTemperature(ts, loc.lat, loc.long, 4000, 6500)
+				temp, sun := c.Temperature(ts, 4000, 6500)
+
+				if temp != expectTemp || sun != expectSun {
+					t.Fatalf("%s: expected %.0fK (%v), got %.0fK (%v)", ts, expectTemp, expectSun, temp, sun)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCalculateTemperature(b *testing.B) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+
+	for i := 0; i < b.N; i++ {
+		CalculateTemperature(ts.Add(time.Duration(i%3600)*time.Second), latitude, longitude, 4000, 6500)
+	}
+}
+
+func BenchmarkSunCalculatorTemperature(b *testing.B) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	c := NewSunCalculator(latitude, longitude)
+
+	for i := 0; i < b.N; i++ {
+		c.Temperature(ts.Add(time.Duration(i%3600)*time.Second), 4000, 6500)
+	}
+}
+
+func TestLocationSun(t *testing.T) {
+	loc := Location{Lat: latitude, Long: longitude}
+	start := time.Date(2021, time.November, 5, 0, 0, 0, 0, losAngeles)
+
+	for ts := start; ts.Before(start.AddDate(0, 0, 4)); ts = ts.Add(20 * time.Minute) {
+		if sun, expect := loc.Sun(ts), CalculateSun(ts, loc.Lat, loc.Long); sun != expect {
+			t.Fatalf("%s: expected %v, got %v", ts, expect, sun)
+		}
+	}
+
+	// The same instant in another zone gets its times in that zone.
+	ts := start.UTC()
+	if sun := loc.Sun(ts); sun.Sunrise.Location() != time.UTC {
+		t.Errorf("expected the sunrise in UTC, got %s", sun.Sunrise.Location())
+	}
+}
+
+func TestLocationSunConcurrent(t *testing.T) {
+	loc := Location{Lat: 69.65, Long: 18.96}
+	start := time.Date(2021, time.May, 10, 0, 0, 0, 0, time.UTC)
+
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			for day := 0; day < 30; day++ {
+				ts := start.AddDate(0, 0, day).Add(time.Duration(i) * time.Hour)
+				if sun, expect := loc.Sun(ts), CalculateSun(ts, loc.Lat, loc.Long); sun != expect {
+					t.Errorf("%s: expected %v, got %v", ts, expect, sun)
+					return
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+}
+
+func TestSunCacheBounded(t *testing.T) {
+	c := newSunCache(3)
+	loc := Location{Lat: latitude, Long: longitude}
+	start := time.Date(2021, time.November, 5, 12, 0, 0, 0, losAngeles)
+
+	for day := 0; day < 10; day++ {
+		c.get(start.AddDate(0, 0, day), loc)
+	}
+
+	if n := c.len(); n != 3 {
+		t.Fatalf("expected 3 cached days, got %d", n)
+	}
+
+	// The latest days are kept.
+	for day := 7; day < 10; day++ {
+		key := sunCacheKey{
+			loc:   loc,
+			start: timeTruncateDayLongitude(start.AddDate(0, 0, day), loc.Long).UnixNano(),
+			zone:  losAngeles,
+		}
+		if _, ok := c.suns[key]; !ok {
+			t.Errorf("expected day %d to be cached", day)
+		}
+	}
+}
+
+func BenchmarkLocationSun(b *testing.B) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	loc := Location{Lat: latitude, Long: longitude}
+
+	for i := 0; i < b.N; i++ {
+		loc.Sun(ts.Add(time.Duration(i%3600) * time.Second))
+	}
+}
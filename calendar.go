@@ -0,0 +1,383 @@
+package solar
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DayLength returns the duration between sunrise and sunset. During midnight
+// sun without a sunrise or sunset, the whole day is daylight, and during polar
+// night, there's none.
+func (s Sun) DayLength() time.Duration {
+	switch {
+	case !s.Sunrise.IsZero() && !s.Sunset.IsZero():
+		return s.Sunset.Sub(s.Sunrise)
+	case s.Condition == MidnightSun:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// NightLength returns the rest of the day that isn't DayLength.
+func (s Sun) NightLength() time.Duration {
+	return 24*time.Hour - s.DayLength()
+}
+
+// FillPolar fills the zero Dawn, Sunrise, Sunset and Dusk of the given Sun,
+// such as during polar night or midnight sun, so that charts don't have gaps.
+// prev and next must be the Suns of the days right before and after. Each zero
+// time is filled with the same clock time from prev, or from next if prev
+// doesn't have it either. The Condition is kept as is, so it still reports the
+// true state of the day.
+//
+// To carry the times through a whole polar period, fill the days in order and
+// pass the filled Sun of each day as the prev of the next.
+func FillPolar(sun Sun, prev, next Sun) Sun {
+	fill := func(t *time.Time, prev, next time.Time) {
+		switch {
+		case !t.IsZero():
+		case !prev.IsZero():
+			*t = prev.AddDate(0, 0, 1)
+		case !next.IsZero():
+			*t = next.AddDate(0, 0, -1)
+		}
+	}
+
+	fill(&sun.Dawn, prev.Dawn, next.Dawn)
+	fill(&sun.Sunrise, prev.Sunrise, next.Sunrise)
+	fill(&sun.Sunset, prev.Sunset, next.Sunset)
+	fill(&sun.Dusk, prev.Dusk, next.Dusk)
+
+	return sun
+}
+
+// DayLength calculates the Sun of the given day and returns its DayLength.
+func DayLength(t time.Time, lat, long float64) time.Duration {
+	return CalculateSun(t, lat, long).DayLength()
+}
+
+// HasSunriseAndSunset returns true if the sun both rises and sets on the day of
+// the given date, that is, if CalculateSun would return a NormalSun. It only
+// calculates the hour angles instead of the whole Sun.
+func HasSunriseAndSunset(date time.Time, lat, long float64) bool {
+	d := newSunDay(date, lat, long)
+	return !math.IsNaN(d.hourAngle(startTwilight)) && !math.IsNaN(d.hourAngle(endTwilight))
+}
+
+// DaylightRemaining calculates how much daylight is left on the day of the
+// given time instant, that is, the duration until sunset, along with the
+// fraction of the day's daylight that it is. The whole daylight is remaining
+// before sunrise, and none is remaining after sunset or during polar night.
+// During midnight sun without a sunset, the daylight lasts until the end of the
+// day.
+func DaylightRemaining(t time.Time, lat, long float64) (remaining time.Duration, fraction float64) {
+	sun := calculateSunAt(t, lat, long)
+
+	total := sun.DayLength()
+	if total == 0 {
+		return 0, 0
+	}
+
+	switch {
+	case !sun.Sunrise.IsZero() && !sun.Sunset.IsZero():
+		switch {
+		case t.Before(sun.Sunrise):
+			return total, 1
+		case !t.Before(sun.Sunset):
+			return 0, 0
+		default:
+			remaining = sun.Sunset.Sub(t)
+		}
+	default:
+		y, m, d := t.Date()
+		remaining = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Sub(t)
+	}
+
+	return remaining, float64(remaining) / float64(total)
+}
+
+// TimeUntilSunrise calculates the duration from t until the next sunrise, which
+// is usually today's if it's still ahead, or tomorrow's otherwise. A
+// *ConditionError is returned if the sun doesn't rise on a day before the next
+// sunrise is found, such as during polar night.
+func TimeUntilSunrise(t time.Time, lat, long float64) (time.Duration, error) {
+	// The sunrise of t's date may already be behind t even in the next solar
+	// day if the timezone is far off the longitude, so search forward like
+	// NextTransitionTime does.
+	for i := 0; i <= maxTransitionSearchDays; i++ {
+		sun := CalculateSun(t.AddDate(0, 0, i), lat, long)
+		if sun.Sunrise.IsZero() {
+			return 0, &ConditionError{sun.Condition}
+		}
+		if sun.Sunrise.After(t) {
+			return sun.Sunrise.Sub(t), nil
+		}
+	}
+
+	return 0, fmt.Errorf("no sunrise within %d days", maxTransitionSearchDays)
+}
+
+// MidpointBetween returns the time instant halfway between a and b. The order
+// of a and b doesn't matter.
+func MidpointBetween(a, b time.Time) time.Time {
+	return a.Add(b.Sub(a) / 2)
+}
+
+// MiddleOfNight returns the time instant halfway between the dusk of the given
+// date and the dawn of the next day, which is when the night is the darkest. A
+// zero time is returned if there's no dusk or no next dawn, such as during
+// polar night or midnight sun.
+func MiddleOfNight(date time.Time, lat, long float64) time.Time {
+	dusk := CalculateSun(date, lat, long).Dusk
+	dawn := CalculateSun(date.AddDate(0, 0, 1), lat, long).Dawn
+
+	if dusk.IsZero() || dawn.IsZero() {
+		return time.Time{}
+	}
+
+	return MidpointBetween(dusk, dawn)
+}
+
+// ConditionOutlook returns the sun condition on the day of the given time
+// instant, along with the number of days until the first day with a different
+// condition, such as how many days until the sun rises again during polar
+// night. -1 is returned if the condition doesn't change within a year, like at
+// most latitudes with a normal sun.
+func ConditionOutlook(t time.Time, lat, long float64) (cond SunCondition, daysUntilChange int) {
+	cond = CalculateSun(t, lat, long).Condition
+
+	for i := 1; i <= 366; i++ {
+		if CalculateSun(t.AddDate(0, 0, i), lat, long).Condition != cond {
+			return cond, i
+		}
+	}
+
+	return cond, -1
+}
+
+// FirstDayWithDaylight scans the given year for the first day whose daylight,
+// the duration between sunrise and sunset, is at least the given target. The
+// returned time is the start of that day in UTC. False is returned if no day
+// in the year has enough daylight.
+func FirstDayWithDaylight(year int, lat, long float64, target time.Duration) (time.Time, bool) {
+	day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for ; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		if CalculateSun(day, lat, long).DayLength() >= target {
+			return day, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// EarliestSunrise scans the given year for the day whose sunrise is the
+// earliest in the day on the clock. The returned date is the start of that day
+// in UTC, and t is the sunrise. Due to the equation of time, this isn't on the
+// summer solstice but some days before it. Zero times are returned if the sun
+// never rises in the year.
+func EarliestSunrise(year int, lat, long float64) (date time.Time, t time.Time) {
+	return scanYearEvent(year, lat, long, func(s Sun) time.Time { return s.Sunrise }, false)
+}
+
+// LatestSunset scans the given year for the day whose sunset is the latest in
+// the day on the clock. It is like EarliestSunrise, except the day is some days
+// after the summer solstice.
+func LatestSunset(year int, lat, long float64) (date time.Time, t time.Time) {
+	return scanYearEvent(year, lat, long, func(s Sun) time.Time { return s.Sunset }, true)
+}
+
+// scanYearEvent scans the days of the given year in UTC for the earliest or
+// latest time of the day of the event returned by the given function. Days
+// that the event doesn't happen on are skipped.
+func scanYearEvent(year int, lat, long float64, event func(Sun) time.Time, latest bool) (date time.Time, t time.Time) {
+	var best time.Duration
+
+	day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for ; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		ev := event(CalculateSun(day, lat, long))
+		if ev.IsZero() {
+			continue
+		}
+
+		clock := ev.Sub(day)
+		if t.IsZero() || (latest && clock > best) || (!latest && clock < best) {
+			date, t, best = day, ev, clock
+		}
+	}
+
+	return date, t
+}
+
+// CalculateSunRange calls CalculateSun for the given number of consecutive
+// days, starting from the day of the given time instant. The returned slice is
+// indexed by the day offset from t.
+func CalculateSunRange(t time.Time, days int, lat, long float64) []Sun {
+	if days <= 0 {
+		return nil
+	}
+
+	suns := make([]Sun, days)
+	for i := range suns {
+		suns[i] = CalculateSun(t.AddDate(0, 0, i), lat, long)
+	}
+
+	return suns
+}
+
+// CalculateYear calculates the Sun for every day of the given year in the given
+// location. The returned slice is indexed by the day of the year starting from
+// 0, and each Sun is the same as what CalculateSun returns for that day.
+//
+// It is faster than calling CalculateSun for every day, since the date math is
+// only done once: the solar days of a year start exactly 24 hours apart, as
+// they are taken in the zone's standard time, so each day's start is stepped
+// from the first instead of being truncated from its date.
+func CalculateYear(year int, lat, long float64, loc *time.Location) []Sun {
+	first := time.Date(year, time.January, 1, 12, 0, 0, 0, loc)
+	days := daysInYear(first)
+	start := timeTruncateDayLongitude(first, long)
+
+	suns := make([]Sun, days)
+	for i := range suns {
+		orbitAngle := yearDayOrbitAngle(i+1, float64(days))
+		day := sunDay{
+			start:  start.Add(time.Duration(i) * 24 * time.Hour),
+			lat:    Radians(lat),
+			decl:   sunDeclination(orbitAngle),
+			eqtime: equationOfTime(orbitAngle),
+		}
+		suns[i] = day.sun()
+	}
+
+	return suns
+}
+
+// SunriseDelta calculates how much later the sun rises on the day after the
+// given date than it does on that date, ignoring the 24 hours between the two
+// days. The delta is negative if the sun rises earlier tomorrow. Since the
+// sunrises are compared as time instants, DST changes don't affect the delta.
+// 0 is returned if there's no sunrise on either day.
+func SunriseDelta(date time.Time, lat, long float64) time.Duration {
+	today := CalculateSun(date, lat, long)
+	tomorrow := CalculateSun(date.AddDate(0, 0, 1), lat, long)
+
+	if today.Sunrise.IsZero() || tomorrow.Sunrise.IsZero() {
+		return 0
+	}
+
+	return tomorrow.Sunrise.Sub(today.Sunrise) - 24*time.Hour
+}
+
+// SunriseShiftRate calculates the rate that the sunrise is shifting on the
+// given date, in how much later the sun rises per day. It is the average of the
+// shifts from yesterday to today and from today to tomorrow, so it is the
+// derivative of the sunrise time centered on the date. The rate is near zero
+// around the solstices and the fastest around the equinoxes. 0 is returned if
+// there's no sunrise on any of the three days.
+func SunriseShiftRate(date time.Time, lat, long float64) time.Duration {
+	before := CalculateSun(date.AddDate(0, 0, -1), lat, long)
+	after := CalculateSun(date.AddDate(0, 0, 1), lat, long)
+
+	if before.Sunrise.IsZero() || after.Sunrise.IsZero() || !HasSunriseAndSunset(date, lat, long) {
+		return 0
+	}
+
+	return after.Sunrise.Sub(before.Sunrise)/2 - 24*time.Hour
+}
+
+// AnalemmaExtent describes the extent of the analemma, the figure-eight that
+// the sun traces in the sky at the same clock time throughout a year.
+type AnalemmaExtent struct {
+	// MinEoT and MaxEoT are the extremes of the equation of time, which is how
+	// far the apparent solar time is ahead of the mean solar time.
+	MinEoT, MaxEoT time.Duration
+	// MinDecl and MaxDecl are the extremes of the sun declination in degrees.
+	MinDecl, MaxDecl float64
+}
+
+// AnalemmaBounds scans every day of the given year for the extent of the
+// analemma.
+func AnalemmaBounds(year int) AnalemmaExtent {
+	ext := AnalemmaExtent{
+		MinEoT:  math.MaxInt64,
+		MaxEoT:  math.MinInt64,
+		MinDecl: math.Inf(+1),
+		MaxDecl: math.Inf(-1),
+	}
+
+	day := time.Date(year, time.January, 1, 12, 0, 0, 0, time.UTC)
+	days := float64(daysInYear(day))
+
+	for ; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		orbitAngle := dateOrbitAngle(day, days)
+
+		eot := eqtimeDuration(equationOfTime(orbitAngle))
+		if eot < ext.MinEoT {
+			ext.MinEoT = eot
+		}
+		if eot > ext.MaxEoT {
+			ext.MaxEoT = eot
+		}
+
+		decl := Degrees(sunDeclination(orbitAngle))
+		ext.MinDecl = math.Min(ext.MinDecl, decl)
+		ext.MaxDecl = math.Max(ext.MaxDecl, decl)
+	}
+
+	return ext
+}
+
+// Equinoxes finds the days of the March and September equinoxes in the given
+// year, when the sun declination crosses 0. The returned times are the start
+// of those days in the given location.
+func Equinoxes(year int, loc *time.Location) (march, september time.Time) {
+	var marchDecl, septemberDecl float64
+
+	scanYearDeclination(year, loc, func(day time.Time, decl float64) {
+		decl = math.Abs(decl)
+		switch {
+		case day.Month() <= time.June:
+			if march.IsZero() || decl < marchDecl {
+				march, marchDecl = day, decl
+			}
+		default:
+			if september.IsZero() || decl < septemberDecl {
+				september, septemberDecl = day, decl
+			}
+		}
+	})
+
+	return march, september
+}
+
+// Solstices finds the days of the June and December solstices in the given
+// year, when the sun declination is the highest and the lowest. The returned
+// times are the start of those days in the given location.
+func Solstices(year int, loc *time.Location) (june, december time.Time) {
+	var maxDecl, minDecl float64
+
+	scanYearDeclination(year, loc, func(day time.Time, decl float64) {
+		if june.IsZero() || decl > maxDecl {
+			june, maxDecl = day, decl
+		}
+		if december.IsZero() || decl < minDecl {
+			december, minDecl = day, decl
+		}
+	})
+
+	return june, december
+}
+
+// scanYearDeclination calls fn with the start of every day of the given year in
+// the given location and the sun declination of that day in radians.
+func scanYearDeclination(year int, loc *time.Location, fn func(day time.Time, decl float64)) {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	days := float64(daysInYear(first))
+
+	for day := first; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		fn(day, sunDeclination(dateOrbitAngle(day, days)))
+	}
+}
@@ -0,0 +1,472 @@
+package solar
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFirstDayWithDaylight(t *testing.T) {
+	t.Run("12 hours", func(t *testing.T) {
+		day, ok := FirstDayWithDaylight(2021, latitude, longitude, 12*time.Hour)
+		if !ok {
+			t.Fatal("expected a day with 12 hours of daylight")
+		}
+
+		t.Log("first day with 12 hours of daylight:", day.Format("2006-01-02"))
+
+		// The spring equinox is on March 20.
+		from := time.Date(2021, time.March, 10, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2021, time.April, 20, 0, 0, 0, 0, time.UTC)
+		if day.Before(from) || day.After(to) {
+			t.Errorf("expected a day near the spring equinox, got %s", day)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		day, ok := FirstDayWithDaylight(2021, 0, 0, 20*time.Hour)
+		if ok {
+			t.Errorf("unexpected day with 20 hours of daylight at the equator: %s", day)
+		}
+	})
+}
+
+func TestCalculateSunRange(t *testing.T) {
+	ts := time.Unix(1636333967-epochDay, 0).In(losAngeles)
+
+	suns := CalculateSunRange(ts, 3, latitude, longitude)
+	if len(suns) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(suns))
+	}
+
+	for i, sun := range suns {
+		expect := CalculateSun(ts.AddDate(0, 0, i), latitude, longitude)
+		if sun != expect {
+			t.Errorf("day %d: expected %v, got %v", i, expect, sun)
+		}
+	}
+
+	if suns := CalculateSunRange(ts, 0, latitude, longitude); suns != nil {
+		t.Errorf("expected no days, got %v", suns)
+	}
+}
+
+func TestSunriseDelta(t *testing.T) {
+	at := func(month time.Month, day int) time.Duration {
+		date := time.Date(2021, month, day, 12, 0, 0, 0, losAngeles)
+		return SunriseDelta(date, latitude, longitude)
+	}
+
+	solstice := at(time.June, 21)
+	equinox := at(time.March, 20)
+
+	t.Log("sunrise delta around the summer solstice:", solstice)
+	t.Log("sunrise delta around the spring equinox:", equinox)
+
+	if absDuration(solstice) > 15*time.Second {
+		t.Errorf("expected the delta around the solstice to be near zero, got %v", solstice)
+	}
+	if absDuration(equinox) < 5*absDuration(solstice) || equinox > 0 {
+		t.Errorf("expected the sunrise to get earlier quickly around the equinox, got %v", equinox)
+	}
+
+	if delta := SunriseDelta(time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC), 80, 0); delta != 0 {
+		t.Errorf("expected no delta during the midnight sun, got %v", delta)
+	}
+}
+
+func TestAnalemmaBounds(t *testing.T) {
+	ext := AnalemmaBounds(2021)
+	t.Logf("analemma bounds: %+v", ext)
+
+	if d := absDuration(ext.MaxEoT - 16*time.Minute - 24*time.Second); d > time.Minute {
+		t.Errorf("expected the maximum equation of time to be about +16m24s, got %v", ext.MaxEoT)
+	}
+	if d := absDuration(ext.MinEoT + 14*time.Minute + 12*time.Second); d > time.Minute {
+		t.Errorf("expected the minimum equation of time to be about -14m12s, got %v", ext.MinEoT)
+	}
+
+	if math.Abs(ext.MaxDecl-23.44) > 0.1 {
+		t.Errorf("expected the maximum declination to be about 23.44°, got %.2f°", ext.MaxDecl)
+	}
+	if math.Abs(ext.MinDecl+23.44) > 0.1 {
+		t.Errorf("expected the minimum declination to be about -23.44°, got %.2f°", ext.MinDecl)
+	}
+}
+
+func TestDaylightRemaining(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+	sun := CalculateSun(date, latitude, longitude)
+
+	t.Run("afternoon", func(t *testing.T) {
+		ts := sun.Sunset.Add(-2 * time.Hour)
+
+		remaining, fraction := DaylightRemaining(ts, latitude, longitude)
+		if remaining != 2*time.Hour {
+			t.Errorf("expected 2h remaining, got %v", remaining)
+		}
+		if expect := float64(2*time.Hour) / float64(sun.DayLength()); !feq(fraction, expect) {
+			t.Errorf("expected fraction %.3f, got %.3f", expect, fraction)
+		}
+	})
+
+	t.Run("before sunrise", func(t *testing.T) {
+		remaining, fraction := DaylightRemaining(sun.Sunrise.Add(-time.Hour), latitude, longitude)
+		if remaining != sun.DayLength() || fraction != 1 {
+			t.Errorf("expected the full day, got %v (%.3f)", remaining, fraction)
+		}
+	})
+
+	t.Run("after sunset", func(t *testing.T) {
+		remaining, fraction := DaylightRemaining(sun.Sunset.Add(time.Hour), latitude, longitude)
+		if remaining != 0 || fraction != 0 {
+			t.Errorf("expected no daylight, got %v (%.3f)", remaining, fraction)
+		}
+	})
+
+	t.Run("polar night", func(t *testing.T) {
+		remaining, fraction := DaylightRemaining(date, 80, longitude)
+		if remaining != 0 || fraction != 0 {
+			t.Errorf("expected no daylight, got %v (%.3f)", remaining, fraction)
+		}
+	})
+
+	t.Run("midnight sun", func(t *testing.T) {
+		ts := time.Date(2021, time.June, 21, 18, 0, 0, 0, time.UTC)
+
+		remaining, fraction := DaylightRemaining(ts, 80, 0)
+		if remaining != 6*time.Hour || !feq(fraction, 0.25) {
+			t.Errorf("expected 6h until the end of the day, got %v (%.3f)", remaining, fraction)
+		}
+	})
+}
+
+func TestEarliestSunriseLatestSunset(t *testing.T) {
+	solstice := time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC)
+
+	date, sunrise := EarliestSunrise(2021, latitude, longitude)
+	t.Log("earliest sunrise:", sunrise)
+
+	if days := solstice.Sub(date).Hours() / 24; days < 4 || days > 14 {
+		t.Errorf("expected the earliest sunrise about a week before the solstice, got %s", date)
+	}
+
+	date, sunset := LatestSunset(2021, latitude, longitude)
+	t.Log("latest sunset:", sunset)
+
+	if days := date.Sub(solstice).Hours() / 24; days < 2 || days > 14 {
+		t.Errorf("expected the latest sunset about a week after the solstice, got %s", date)
+	}
+
+	if date, sunrise := EarliestSunrise(2021, 90, 0); !date.IsZero() || !sunrise.IsZero() {
+		t.Errorf("expected no sunrise at the north pole, got %s", sunrise)
+	}
+}
+
+func TestTimeUntilSunrise(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+	today := CalculateSun(date, latitude, longitude)
+	tomorrow := CalculateSun(date.AddDate(0, 0, 1), latitude, longitude)
+
+	t.Run("before sunrise", func(t *testing.T) {
+		ts := today.Sunrise.Add(-time.Hour)
+
+		d, err := TimeUntilSunrise(ts, latitude, longitude)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if d != time.Hour {
+			t.Errorf("expected today's sunrise in 1h, got %v", d)
+		}
+	})
+
+	t.Run("after sunrise", func(t *testing.T) {
+		ts := today.Sunrise.Add(time.Hour)
+
+		d, err := TimeUntilSunrise(ts, latitude, longitude)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if expect := tomorrow.Sunrise.Sub(ts); d != expect {
+			t.Errorf("expected tomorrow's sunrise in %v, got %v", expect, d)
+		}
+	})
+
+	t.Run("mismatched zone", func(t *testing.T) {
+		// With a UTC clock near the date line, the sunrises of both today and
+		// tomorrow are already behind t.
+		ts := time.Date(2021, time.November, 8, 18, 30, 0, 0, time.UTC)
+
+		for _, long := range []float64{179, 150, 100} {
+			d, err := TimeUntilSunrise(ts, 10, long)
+			if err != nil {
+				t.Fatalf("%g: unexpected error: %v", long, err)
+			}
+			if d <= 0 || d > 24*time.Hour {
+				t.Errorf("%g: expected the next sunrise within a day, got %v", long, d)
+			}
+			if elevation := SunElevation(ts.Add(d), 10, long); math.Abs(elevation-2.167) > 0.1 {
+				t.Errorf("%g: expected the sun at the sunrise elevation, got %.2f°", long, elevation)
+			}
+		}
+	})
+
+	t.Run("polar night", func(t *testing.T) {
+		_, err := TimeUntilSunrise(date, 80, 0)
+
+		var condErr *ConditionError
+		if !errors.As(err, &condErr) || condErr.Condition != PolarNightSun {
+			t.Errorf("expected a polar night error, got %v", err)
+		}
+	})
+}
+
+func TestDayLength(t *testing.T) {
+	date := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+	t.Run("normal", func(t *testing.T) {
+		sun := CalculateSun(date, latitude, longitude)
+		if d := sun.DayLength(); d != sun.Sunset.Sub(sun.Sunrise) {
+			t.Errorf("expected the span from sunrise to sunset, got %v", d)
+		}
+		if d := sun.DayLength() + sun.NightLength(); d != 24*time.Hour {
+			t.Errorf("expected the day and night to add up to 24h, got %v", d)
+		}
+		if d := DayLength(date, latitude, longitude); d != sun.DayLength() {
+			t.Errorf("expected DayLength to match the Sun's, got %v", d)
+		}
+	})
+
+	t.Run("midnight sun", func(t *testing.T) {
+		sun := CalculateSun(date, 85, 0)
+		if sun.DayLength() != 24*time.Hour || sun.NightLength() != 0 {
+			t.Errorf("expected a 24h day, got %v and %v", sun.DayLength(), sun.NightLength())
+		}
+	})
+
+	t.Run("polar night", func(t *testing.T) {
+		sun := CalculateSun(date, -85, 0)
+		if sun.DayLength() != 0 || sun.NightLength() != 24*time.Hour {
+			t.Errorf("expected a 24h night, got %v and %v", sun.DayLength(), sun.NightLength())
+		}
+	})
+}
+
+func TestHasSunriseAndSunset(t *testing.T) {
+	day := time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	for ; day.Year() == 2021; day = day.AddDate(0, 0, 1) {
+		if !HasSunriseAndSunset(day, latitude, longitude) {
+			t.Fatalf("%s: expected a sunrise and sunset at a mid latitude", day.Format("2006-01-02"))
+		}
+	}
+
+	for _, date := range []time.Time{
+		time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC),
+		time.Date(2021, time.December, 21, 12, 0, 0, 0, time.UTC),
+	} {
+		if HasSunriseAndSunset(date, 78.2, 15.6) {
+			t.Errorf("%s: expected no sunrise and sunset in Svalbard", date.Format("2006-01-02"))
+		}
+		if got := CalculateSun(date, 78.2, 15.6).Condition; got == NormalSun {
+			t.Errorf("%s: expected CalculateSun to agree, got %s", date.Format("2006-01-02"), got)
+		}
+	}
+}
+
+func TestCalculateYear(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Fatal("cannot load Australia/Sydney:", err)
+	}
+
+	tests := []struct {
+		lat  float64
+		long float64
+		loc  *time.Location
+	}{
+		{latitude, longitude, losAngeles},
+		{-33.87, 151.21, sydney},
+		{10, -179, time.UTC},
+	}
+
+	for _, test := range tests {
+		for _, year := range []int{2021, 2024} {
+			suns := CalculateYear(year, test.lat, test.long, test.loc)
+			if expect := daysInYear(time.Date(year, time.June, 1, 0, 0, 0, 0, time.UTC)); len(suns) != expect {
+				t.Fatalf("%s %d: expected %d days, got %d", test.loc, year, expect, len(suns))
+			}
+
+			for i, sun := range suns {
+				date := time.Date(year, time.January, 1+i, 0, 0, 0, 0, test.loc)
+				if expect := CalculateSun(date, test.lat, test.long); sun != expect {
+					t.Errorf("%s %s: expected %v, got %v", test.loc, date.Format("2006-01-02"), expect, sun)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkCalculateYear(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CalculateYear(2021, latitude, longitude, losAngeles)
+	}
+}
+
+func BenchmarkCalculateYearNaive(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		suns := make([]Sun, 0, 365)
+		for day := 0; day < 365; day++ {
+			date := time.Date(2021, time.January, 1+day, 12, 0, 0, 0, losAngeles)
+			suns = append(suns, CalculateSun(date, latitude, longitude))
+		}
+	}
+}
+
+func TestSunriseShiftRate(t *testing.T) {
+	solstice := SunriseShiftRate(time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC), latitude, longitude)
+	equinox := SunriseShiftRate(time.Date(2021, time.March, 20, 12, 0, 0, 0, time.UTC), latitude, longitude)
+	t.Logf("solstice: %v, equinox: %v", solstice, equinox)
+
+	if solstice < -20*time.Second || solstice > 20*time.Second {
+		t.Errorf("expected a near zero rate at the solstice, got %v", solstice)
+	}
+	// The mornings are getting earlier in March.
+	if equinox > -time.Minute {
+		t.Errorf("expected the sunrise to be over a minute earlier per day at the equinox, got %v", equinox)
+	}
+
+	if rate := SunriseShiftRate(time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC), -85, 0); rate != 0 {
+		t.Errorf("expected no rate during polar night, got %v", rate)
+	}
+}
+
+func TestMiddleOfNight(t *testing.T) {
+	a := time.Date(2021, time.November, 8, 6, 0, 0, 0, time.UTC)
+	b := a.Add(3 * time.Hour)
+	if mid := MidpointBetween(a, b); !mid.Equal(a.Add(90 * time.Minute)) {
+		t.Errorf("expected the midpoint 90 minutes after a, got %s", mid)
+	}
+	if mid := MidpointBetween(b, a); !mid.Equal(a.Add(90 * time.Minute)) {
+		t.Errorf("expected the order not to matter, got %s", mid)
+	}
+
+	date := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	sun := CalculateSun(date, latitude, longitude)
+	noon := MidpointBetween(sun.Sunrise, sun.Sunset)
+
+	mid := MiddleOfNight(date, latitude, longitude)
+	t.Log("middle of night:", mid)
+
+	if diff := mid.Sub(noon.Add(12 * time.Hour)); diff < -5*time.Minute || diff > 5*time.Minute {
+		t.Errorf("expected the middle of night to be opposite of noon at %s, got %s", noon, mid)
+	}
+
+	if mid := MiddleOfNight(time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC), 85, 0); !mid.IsZero() {
+		t.Errorf("expected no middle of night during midnight sun, got %s", mid)
+	}
+}
+
+func TestConditionOutlook(t *testing.T) {
+	winter := time.Date(2021, time.December, 21, 12, 0, 0, 0, time.UTC)
+
+	cond, days := ConditionOutlook(winter, 78.2, 15.6)
+	t.Logf("Svalbard on the winter solstice: %s, %d days until it changes", cond, days)
+
+	if cond != PolarNightSun {
+		t.Fatalf("expected polar night, got %s", cond)
+	}
+	if days <= 0 {
+		t.Fatalf("expected a positive number of days, got %d", days)
+	}
+
+	change := winter.AddDate(0, 0, days)
+	if got := CalculateSun(change, 78.2, 15.6).Condition; got != NormalSun {
+		t.Errorf("expected a normal sun on %s, got %s", change.Format("2006-01-02"), got)
+	}
+	if got := CalculateSun(change.AddDate(0, 0, -1), 78.2, 15.6).Condition; got != PolarNightSun {
+		t.Errorf("expected polar night the day before, got %s", got)
+	}
+
+	if cond, days := ConditionOutlook(winter, latitude, longitude); cond != NormalSun || days != -1 {
+		t.Errorf("expected a normal sun that never changes, got %s in %d days", cond, days)
+	}
+}
+
+func TestFillPolar(t *testing.T) {
+	const lat, long = 69.65, 18.96 // Tromsø
+
+	start := time.Date(2021, time.April, 1, 12, 0, 0, 0, time.UTC)
+	suns := CalculateSunRange(start, 90, lat, long)
+
+	var polar int
+	filled := make([]Sun, len(suns))
+	for i, sun := range suns {
+		if i == 0 {
+			if sun.Condition != NormalSun {
+				t.Fatalf("expected a normal sun on the first day, got %s", sun.Condition)
+			}
+			filled[i] = sun
+			continue
+		}
+
+		var next Sun
+		if i+1 < len(suns) {
+			next = suns[i+1]
+		}
+
+		filled[i] = FillPolar(sun, filled[i-1], next)
+		if filled[i].Condition != sun.Condition {
+			t.Errorf("day %d: expected the condition %s to be kept, got %s", i, sun.Condition, filled[i].Condition)
+		}
+		if sun.Sunrise.IsZero() {
+			polar++
+		}
+		if sun.Condition == NormalSun && filled[i] != sun {
+			t.Errorf("day %d: expected a normal day to be unchanged", i)
+		}
+	}
+
+	if polar == 0 {
+		t.Fatal("expected the midnight sun to start within the range")
+	}
+
+	for i, sun := range filled {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		for _, event := range []time.Time{sun.Dawn, sun.Sunrise, sun.Sunset, sun.Dusk} {
+			if event.IsZero() {
+				t.Errorf("%s: expected all events to be filled", date)
+			}
+		}
+		if i > 0 {
+			if d := sun.Sunrise.Sub(filled[i-1].Sunrise); d < 23*time.Hour || d > 25*time.Hour {
+				t.Errorf("%s: expected the sunrise about a day after the last, got %v", date, d)
+			}
+		}
+	}
+}
+
+func TestEquinoxesSolstices(t *testing.T) {
+	assertDay := func(name string, got time.Time, month time.Month, day int) {
+		t.Helper()
+
+		expect := time.Date(2021, month, day, 0, 0, 0, 0, losAngeles)
+		if d := got.Sub(expect); d < -24*time.Hour || d > 24*time.Hour {
+			t.Errorf("%s: expected around %s, got %s", name, expect.Format("2006-01-02"), got.Format("2006-01-02"))
+		}
+		if got.Location() != losAngeles {
+			t.Errorf("%s: expected the day in America/Los_Angeles, got %s", name, got.Location())
+		}
+	}
+
+	march, september := Equinoxes(2021, losAngeles)
+	assertDay("March equinox", march, time.March, 20)
+	assertDay("September equinox", september, time.September, 22)
+
+	june, december := Solstices(2021, losAngeles)
+	assertDay("June solstice", june, time.June, 21)
+	assertDay("December solstice", december, time.December, 21)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/solar"
+)
+
+// applyMain is the main function of the apply subcommand, which applies the
+// current color temperature to the display. It is only supported on some
+// platforms; see applyGammaRamp.
+func applyMain(args []string) error {
+	var (
+		lat  = 0.0
+		long = solar.LocalLongitude()
+		lo   = float64(solar.DefaultLowTemperature)
+		hi   = float64(solar.DefaultHighTemperature)
+	)
+
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	fs.Var(latitudeValue(&lat), "lat", "latitude in decimal degrees or DMS")
+	fs.Var(longitudeValue(&long), "long", "longitude in decimal degrees or DMS, estimated from the timezone if not set")
+	fs.Float64Var(&lo, "lo", lo, "lowest temperature in Kelvin")
+	fs.Float64Var(&hi, "hi", hi, "highest temperature in Kelvin")
+	fs.Parse(args)
+
+	temp, _ := solar.CalculateTemperature(time.Now(), lat, long, solar.Temperature(lo), solar.Temperature(hi))
+
+	if err := applyGammaRamp(buildGammaRamp(temp)); err != nil {
+		return fmt.Errorf("cannot apply %.0fK: %w", temp, err)
+	}
+
+	return nil
+}
+
+// gammaRamp is a gamma ramp in the layout that SetDeviceGammaRamp takes: 256
+// 16-bit entries for each of the red, green and blue channels.
+type gammaRamp [3][256]uint16
+
+// buildGammaRamp builds the gamma ramp for the given color temperature using
+// solar.GammaRamp.
+func buildGammaRamp(temp solar.Temperature) *gammaRamp {
+	var ramp gammaRamp
+	r, g, b := solar.GammaRamp(temp, len(ramp[0]))
+	copy(ramp[0][:], r)
+	copy(ramp[1][:], g)
+	copy(ramp[2][:], b)
+	return &ramp
+}
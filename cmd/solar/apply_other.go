@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// applyGammaRamp applies the gamma ramp to the primary display. This is
+// currently only supported on Windows.
+var applyGammaRamp = func(ramp *gammaRamp) error {
+	return errors.New("solar apply is only supported on Windows")
+}
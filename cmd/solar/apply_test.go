@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/diamondburned/solar"
+)
+
+func TestBuildGammaRamp(t *testing.T) {
+	ramp := buildGammaRamp(6500)
+	for c := range ramp {
+		if ramp[c][255] != math.MaxUint16 {
+			t.Errorf("channel %d: expected the full ramp to end at %d, got %d", c, math.MaxUint16, ramp[c][255])
+		}
+	}
+
+	ramp = buildGammaRamp(3000)
+	r, g, b := solar.GammaRamp(3000, 256)
+	for c, expect := range [][]uint16{r, g, b} {
+		for i, v := range expect {
+			if ramp[c][i] != v {
+				t.Fatalf("channel %d: expected %d at %d, got %d", c, v, i, ramp[c][i])
+			}
+		}
+	}
+
+	if ramp[2][255] >= ramp[1][255] || ramp[1][255] >= ramp[0][255] {
+		t.Errorf("expected a warm ramp at 3000K, got ends %d, %d, %d", ramp[0][255], ramp[1][255], ramp[2][255])
+	}
+
+	for c := range ramp {
+		for i := 1; i < len(ramp[c]); i++ {
+			if ramp[c][i] < ramp[c][i-1] {
+				t.Fatalf("channel %d: ramp decreases at %d", c, i)
+			}
+		}
+	}
+}
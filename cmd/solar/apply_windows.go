@@ -0,0 +1,46 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+	gdi32  = syscall.NewLazyDLL("gdi32.dll")
+
+	procGetDC              = user32.NewProc("GetDC")
+	procReleaseDC          = user32.NewProc("ReleaseDC")
+	procSetDeviceGammaRamp = gdi32.NewProc("SetDeviceGammaRamp")
+)
+
+// applyGammaRamp applies the gamma ramp to the primary display. It is a
+// variable so that tests can replace it.
+var applyGammaRamp = func(ramp *gammaRamp) error {
+	hdc, _, err := procGetDC.Call(0)
+	if hdc == 0 {
+		return callError("cannot get the screen device context", err)
+	}
+	defer procReleaseDC.Call(0, hdc)
+
+	ok, _, err := procSetDeviceGammaRamp.Call(hdc, uintptr(unsafe.Pointer(ramp)))
+	if ok == 0 {
+		return callError("SetDeviceGammaRamp failed", err)
+	}
+
+	return nil
+}
+
+// callError returns an error with the given message for a failed call. The
+// error from the call is only wrapped if it's a non-zero Errno, since the calls
+// always return an error, which is Errno(0) if the function didn't set one.
+func callError(msg string, err error) error {
+	if errno, ok := err.(syscall.Errno); ok && errno != 0 {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return errors.New(msg)
+}
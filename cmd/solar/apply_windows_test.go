@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestApplyMain(t *testing.T) {
+	var applied *gammaRamp
+
+	old := applyGammaRamp
+	applyGammaRamp = func(ramp *gammaRamp) error {
+		applied = ramp
+		return nil
+	}
+	defer func() { applyGammaRamp = old }()
+
+	if err := applyMain([]string{"-lat", "34.1", "-long", "-118.2"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if applied == nil {
+		t.Fatal("expected a gamma ramp to be applied")
+	}
+
+	for c := range applied {
+		if applied[c][0] != 0 {
+			t.Errorf("channel %d: expected the ramp to start at 0, got %d", c, applied[c][0])
+		}
+		for i := 1; i < len(applied[c]); i++ {
+			if applied[c][i] < applied[c][i-1] {
+				t.Fatalf("channel %d: ramp decreases at %d", c, i)
+			}
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	if err := callError("failed", syscall.Errno(0)); err.Error() != "failed" {
+		t.Errorf("expected Errno(0) to be dropped, got %q", err)
+	}
+
+	const errno = syscall.ERROR_ACCESS_DENIED
+	if err := callError("failed", errno); !errors.Is(err, errno) {
+		t.Errorf("expected the non-zero Errno to be wrapped, got %q", err)
+	}
+}
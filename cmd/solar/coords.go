@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// coordValue is a flag.Value for a latitude or longitude. It accepts either
+// decimal degrees or degrees-minutes-seconds, such as 34°06'00"N.
+type coordValue struct {
+	v   *float64
+	pos rune // N or E
+	neg rune // S or W
+	max float64
+}
+
+func latitudeValue(v *float64) *coordValue {
+	return &coordValue{v: v, pos: 'N', neg: 'S', max: 90}
+}
+
+func longitudeValue(v *float64) *coordValue {
+	return &coordValue{v: v, pos: 'E', neg: 'W', max: 180}
+}
+
+func (c *coordValue) String() string {
+	if c.v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*c.v, 'g', -1, 64)
+}
+
+func (c *coordValue) Set(s string) error {
+	deg, err := parseCoord(s, c.pos, c.neg)
+	if err != nil {
+		return err
+	}
+	if deg < -c.max || deg > c.max {
+		return fmt.Errorf("%g is out of range [-%g, %g]", deg, c.max, c.max)
+	}
+	*c.v = deg
+	return nil
+}
+
+// parseCoord parses the given coordinate in either decimal degrees or
+// degrees-minutes-seconds into decimal degrees. The coordinate may end with
+// either the given positive or negative hemisphere letter.
+func parseCoord(s string, pos, neg rune) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty coordinate")
+	}
+
+	sign := 1.0
+
+	if r, size := utf8.DecodeLastRuneInString(s); unicode.IsLetter(r) {
+		r = unicode.ToUpper(r)
+		switch r {
+		case pos:
+		case neg:
+			sign = -1
+		default:
+			return 0, fmt.Errorf("unknown hemisphere %q, must be %c or %c", r, pos, neg)
+		}
+		s = strings.TrimSpace(s[:len(s)-size])
+	}
+
+	if strings.HasPrefix(s, "-") {
+		if sign < 0 {
+			return 0, errors.New("coordinate has both a sign and a negative hemisphere")
+		}
+		sign = -1
+		s = s[1:]
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`°'"′″`, r)
+	})
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+
+	var deg float64
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid coordinate %q: %w", s, err)
+		}
+		if v < 0 || (i > 0 && v >= 60) {
+			return 0, fmt.Errorf("invalid coordinate %q: %q is out of range", s, field)
+		}
+		deg += v / [...]float64{1, 60, 3600}[i]
+	}
+
+	return sign * deg, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseCoord(t *testing.T) {
+	tests := []struct {
+		in     string
+		long   bool
+		expect float64
+	}{
+		{`34.1`, false, 34.1},
+		{`-118.2`, true, -118.2},
+		{`34°06'00"N`, false, 34.1},
+		{`34°06′00″N`, false, 34.1},
+		{`34° 6' 0" n`, false, 34.1},
+		{`34 06 00 N`, false, 34.1},
+		{`34°6'N`, false, 34.1},
+		{`34.1N`, false, 34.1},
+		{`33°54'S`, false, -33.9},
+		{`118°12'W`, true, -118.2},
+		{`-118°12'`, true, -118.2},
+		{`151°12'36"E`, true, 151.21},
+		{`34.1°`, false, 34.1},
+		{`34°06′`, false, 34.1},
+	}
+
+	for _, test := range tests {
+		pos, neg := 'N', 'S'
+		if test.long {
+			pos, neg = 'E', 'W'
+		}
+
+		v, err := parseCoord(test.in, pos, neg)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.in, err)
+			continue
+		}
+		if math.Abs(v-test.expect) > 1e-9 {
+			t.Errorf("%s: expected %g, got %g", test.in, test.expect, v)
+		}
+	}
+}
+
+func TestParseCoordInvalid(t *testing.T) {
+	tests := []string{
+		``,
+		`abc`,
+		`34°60'N`,
+		`34°06'00"E`,
+		`-34°06'S`,
+		`1 2 3 4`,
+	}
+
+	for _, test := range tests {
+		if v, err := parseCoord(test, 'N', 'S'); err == nil {
+			t.Errorf("%q: expected an error, got %g", test, v)
+		}
+	}
+}
+
+func TestParseCoordMultibyteHemisphere(t *testing.T) {
+	// A full-width Ｎ is reported as is instead of as one of its bytes.
+	_, err := parseCoord(`34°06'Ｎ`, 'N', 'S')
+	if err == nil || !strings.Contains(err.Error(), `'Ｎ'`) {
+		t.Errorf("expected an unknown hemisphere Ｎ error, got %v", err)
+	}
+}
+
+func TestCoordValue(t *testing.T) {
+	var lat float64
+	if err := latitudeValue(&lat).Set(`91°N`); err == nil {
+		t.Error("expected an out of range latitude to be rejected")
+	}
+	if err := latitudeValue(&lat).Set(`34°06'N`); err != nil || math.Abs(lat-34.1) > 1e-9 {
+		t.Errorf("expected 34.1, got %g (error %v)", lat, err)
+	}
+}
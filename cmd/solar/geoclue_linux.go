@@ -0,0 +1,112 @@
+//go:build geoclue
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	geoclueService   = "org.freedesktop.GeoClue2"
+	geoclueManager   = "/org/freedesktop/GeoClue2/Manager"
+	geoclueClient    = geoclueService + ".Client"
+	geoclueLocIface  = geoclueService + ".Location"
+	geoclueDesktopID = "solar"
+
+	// geoclueAccuracyCity is GCLUE_ACCURACY_LEVEL_CITY, which is plenty for
+	// calculating the sun times.
+	geoclueAccuracyCity = uint32(4)
+)
+
+// geoclueLocation asks GeoClue over the system DBus for the current latitude
+// and longitude. It blocks until GeoClue reports a location or ctx is done.
+func geoclueLocation(ctx context.Context) (lat, long float64, err error) {
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot connect to system bus: %w", err)
+	}
+	defer conn.Close()
+
+	var clientPath dbus.ObjectPath
+	manager := conn.Object(geoclueService, geoclueManager)
+	if err := manager.CallWithContext(ctx, geoclueService+".Manager.GetClient", 0).Store(&clientPath); err != nil {
+		return 0, 0, fmt.Errorf("cannot get GeoClue client: %w", err)
+	}
+
+	client := conn.Object(geoclueService, clientPath)
+	if err := client.SetProperty(geoclueClient+".DesktopId", dbus.MakeVariant(geoclueDesktopID)); err != nil {
+		return 0, 0, fmt.Errorf("cannot set GeoClue desktop ID: %w", err)
+	}
+	if err := client.SetProperty(geoclueClient+".RequestedAccuracyLevel", dbus.MakeVariant(geoclueAccuracyCity)); err != nil {
+		return 0, 0, fmt.Errorf("cannot set GeoClue accuracy level: %w", err)
+	}
+
+	if err := conn.AddMatchSignalContext(ctx,
+		dbus.WithMatchObjectPath(clientPath),
+		dbus.WithMatchInterface(geoclueClient),
+		dbus.WithMatchMember("LocationUpdated"),
+	); err != nil {
+		return 0, 0, fmt.Errorf("cannot subscribe to GeoClue location updates: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	if err := client.CallWithContext(ctx, geoclueClient+".Start", 0).Err; err != nil {
+		return 0, 0, fmt.Errorf("cannot start GeoClue client: %w", err)
+	}
+	defer client.Call(geoclueClient+".Stop", 0)
+
+	var locationPath dbus.ObjectPath
+
+	for locationPath == "" {
+		select {
+		case <-ctx.Done():
+			return 0, 0, ctx.Err()
+		case sig, ok := <-signals:
+			if !ok {
+				return 0, 0, errors.New("DBus connection closed")
+			}
+			// LocationUpdated has the old and new location paths.
+			if sig.Path == clientPath && len(sig.Body) == 2 {
+				locationPath, _ = sig.Body[1].(dbus.ObjectPath)
+			}
+		}
+	}
+
+	var props map[string]dbus.Variant
+	location := conn.Object(geoclueService, locationPath)
+	if err := location.CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, geoclueLocIface).Store(&props); err != nil {
+		return 0, 0, fmt.Errorf("cannot get GeoClue location: %w", err)
+	}
+
+	return parseGeoclueLocation(props)
+}
+
+// parseGeoclueLocation parses the properties of a GeoClue Location object.
+func parseGeoclueLocation(props map[string]dbus.Variant) (lat, long float64, err error) {
+	get := func(name string) (float64, error) {
+		v, ok := props[name]
+		if !ok {
+			return 0, fmt.Errorf("missing %s", name)
+		}
+		f, ok := v.Value().(float64)
+		if !ok {
+			return 0, fmt.Errorf("unexpected %s type %s", name, v.Signature())
+		}
+		return f, nil
+	}
+
+	if lat, err = get("Latitude"); err != nil {
+		return 0, 0, err
+	}
+	if long, err = get("Longitude"); err != nil {
+		return 0, 0, err
+	}
+
+	return lat, long, nil
+}
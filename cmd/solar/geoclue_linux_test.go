@@ -0,0 +1,40 @@
+//go:build geoclue
+
+package main
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestParseGeoclueLocation(t *testing.T) {
+	// This is what org.freedesktop.DBus.Properties.GetAll returns for a
+	// org.freedesktop.GeoClue2.Location object.
+	props := map[string]dbus.Variant{
+		"Latitude":    dbus.MakeVariant(34.1),
+		"Longitude":   dbus.MakeVariant(-118.2),
+		"Accuracy":    dbus.MakeVariant(25000.0),
+		"Altitude":    dbus.MakeVariant(-1.7976931348623157e+308),
+		"Description": dbus.MakeVariant("GeoIP"),
+	}
+
+	lat, long, err := parseGeoclueLocation(props)
+	if err != nil {
+		t.Fatal("cannot parse location:", err)
+	}
+
+	if lat != 34.1 || long != -118.2 {
+		t.Fatalf("expected 34.1, -118.2, got %g, %g", lat, long)
+	}
+
+	delete(props, "Longitude")
+	if _, _, err := parseGeoclueLocation(props); err == nil {
+		t.Fatal("expected an error for a missing longitude")
+	}
+
+	props["Longitude"] = dbus.MakeVariant("-118.2")
+	if _, _, err := parseGeoclueLocation(props); err == nil {
+		t.Fatal("expected an error for a string longitude")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux || !geoclue
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func geoclueLocation(ctx context.Context) (lat, long float64, err error) {
+	return 0, 0, errors.New("solar was built without GeoClue support, rebuild with -tags geoclue")
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/diamondburned/solar"
@@ -16,33 +20,100 @@ import (
 
 var (
 	latitude  = 0.0
-	longitude = solar.LocalLongitude()
+	longitude = 0.0
 	lowTemp   = float64(solar.DefaultLowTemperature)
 	highTemp  = float64(solar.DefaultHighTemperature)
 	tformat   = "15:04:05"
 	tnow      = time.Now().Unix()
+	tstring   = ""
 	address   = ""
 	useIPLoc  = false
+	geoclue   = false
 	printJSON = false
+	repeat    = time.Duration(0)
+	watch     = false
+	days      = 1
+	noDSTAdj  = false
+	status    = false
+	zone      = "local"
+	tz        = ""
+	redshift  = false
+	serve     = ""
+	verbose   = false
+	graph     = false
+	apiVer    = latestAPIVersion
+	wp        = false
+	hex       = false
 )
 
+// geocodeResults is set if the coordinates were geocoded.
+var geocodeResults *GeocodeResults
+
 func main() {
-	flag.Float64Var(&latitude, "lat", latitude, "latitude")
-	flag.Float64Var(&longitude, "long", longitude, "longitude, optional")
+	if len(os.Args) > 1 {
+		subcommands := map[string]func([]string) error{
+			"year":  yearMain,
+			"apply": applyMain,
+		}
+
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+	}
+
+	flag.Var(latitudeValue(&latitude), "lat", "latitude in decimal degrees or DMS, such as 34°06'00\"N")
+	flag.Var(longitudeValue(&longitude), "long", "longitude in decimal degrees or DMS, estimated from the timezone if not set")
 	flag.Float64Var(&lowTemp, "lo", lowTemp, "lowest temperature in Kelvin")
 	flag.Float64Var(&highTemp, "hi", highTemp, "highest temperature in Kelvin")
 	flag.StringVar(&tformat, "t", tformat, "time format")
 	flag.StringVar(&address, "a", address, "address to geocode, takes precedence over --lat, --long and --ip")
 	flag.Int64Var(&tnow, "now", tnow, "current time in Unix seconds")
+	flag.StringVar(&tstring, "time", tstring, "current time in RFC3339, such as 2021-11-07T17:12:47-08:00, overrides --now")
+	flag.StringVar(&zone, "zone", zone, "timezone to interpret --now and print times in: local or utc, defaults to the offset of --time if set")
+	flag.StringVar(&tz, "tz", tz, "IANA timezone to interpret --now and --time and print times in, such as America/New_York, overrides --zone")
 	flag.BoolVar(&printJSON, "j", printJSON, "print JSON instead of human-readable")
 	flag.BoolVar(&useIPLoc, "ip", useIPLoc, "use IP location instead of coordinates")
+	flag.BoolVar(&geoclue, "geoclue", geoclue, "use GeoClue location instead of coordinates, Linux only")
+	flag.DurationVar(&repeat, "repeat", repeat, "reprint the results every given interval until interrupted")
+	flag.BoolVar(&watch, "watch", watch, "reprint the results at every transition and during ramps until interrupted, one JSON object per line with -j")
+	flag.IntVar(&days, "days", days, "number of days to print the sun data for, starting today")
+	flag.BoolVar(&noDSTAdj, "no-dst-adjust", noDSTAdj, "don't undo DST when estimating the longitude from the timezone")
+	flag.BoolVar(&status, "status", status, "print a short line for status bars instead of human-readable")
+	flag.BoolVar(&redshift, "redshift", redshift, "print only the color temperature for redshift -O")
+	flag.BoolVar(&graph, "graph", graph, "also print the temperature throughout the day as a sparkline")
+	flag.BoolVar(&wp, "wp", wp, "also print the RGB whitepoint of the temperature")
+	flag.BoolVar(&hex, "hex", hex, "also print the whitepoint of the temperature as #rrggbb")
+	flag.BoolVar(&verbose, "v", verbose, "print warnings about the given location")
+	flag.StringVar(&serve, "serve", serve, "serve the conditions of multiple locations over HTTP at the given address")
+	flag.IntVar(&apiVer, "api-version", apiVer, "version of the -j output shape, 1 for the original one")
 	flag.Parse()
 
-	var geocodeResponse *geocodeResponse
-	var geocodeResults *GeocodeResults
+	if watch && repeat > 0 {
+		log.Fatalln("--watch and --repeat cannot be used together")
+	}
+
+	if apiVer < 1 || apiVer > latestAPIVersion {
+		log.Fatalf("invalid --api-version: must be between 1 and %d", latestAPIVersion)
+	}
+
+	loc, err := flagLocation(zone, tz)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	now, err := currentTime(tnow, tstring, loc, isFlagSet("zone") || tz != "")
+	if err != nil {
+		log.Fatalln("invalid --time:", err)
+	}
+
+	if !isFlagSet("long") {
+		longitude = estimateLongitude(now, loc, tz != "")
+	}
 
 	if useIPLoc || address != "" {
-		var err error
 		geocodeInput := address
 
 		switch {
@@ -55,7 +126,7 @@ func main() {
 			geocodeInput = address
 		}
 
-		geocodeResponse, err = geocode(geocodeInput)
+		geocodeResponse, err := geocode(geocodeInput)
 		if err != nil {
 			log.Fatalln("cannot geolocate from public IP:", err)
 		}
@@ -69,40 +140,274 @@ func main() {
 		}
 	}
 
+	if geoclue && !useIPLoc && address == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		latitude, longitude, err = geoclueLocation(ctx)
+		if err != nil {
+			log.Fatalln("cannot get GeoClue location:", err)
+		}
+	}
+
+	if verbose {
+		if offBy, ok := solar.CheckLongitudeConsistency(now, longitude); !ok {
+			log.Printf("warning: longitude %g is %v off from the %s timezone, times may be off", longitude, offBy, now.Location())
+		}
+	}
+
+	if serve != "" {
+		log.Fatalln(http.ListenAndServe(serve, newServeMux(func() time.Time {
+			return time.Now().In(loc)
+		})))
+	}
+
+	print := func(now time.Time) {
+		r := calculate(now)
+
+		switch {
+		case status:
+			_, err = fmt.Println(r.StatusLine())
+		case redshift:
+			_, err = fmt.Println(r.RedshiftTemperature())
+		case printJSON && watch:
+			err = r.PrintJSONLine(os.Stdout)
+		case printJSON:
+			err = r.PrintJSON(os.Stdout)
+		default:
+			err = r.PrintText(os.Stdout)
+		}
+
+		if err != nil {
+			log.Fatalln("cannot print results:", err)
+		}
+	}
+
+	if repeat <= 0 && !watch {
+		print(now)
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if watch {
+		// The watcher follows the wall clock, so --now is not used here.
+		lo := solar.Temperature(lowTemp)
+		hi := solar.Temperature(highTemp)
+		temps := solar.WatchCurrentTemperature(ctx, latitude, longitude, lo, hi)
+		printWatch(temps, func() { print(time.Now().In(loc)) })
+		return
+	}
+
+	ticker := time.NewTicker(repeat)
+	defer ticker.Stop()
+
+	// Shift the given time along with the ticker, so that an explicit --now
+	// keeps advancing.
+	start := time.Now()
+	printRepeat(ctx, ticker.C, func(t time.Time) {
+		print(now.Add(t.Sub(start)))
+	})
+}
+
+// flagLocation returns the location for the given --zone and --tz values. An
+// IANA timezone given to --tz takes precedence over --zone.
+func flagLocation(zone, tz string) (*time.Location, error) {
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tz: %w", err)
+		}
+		return loc, nil
+	}
+
+	loc, err := zoneLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --zone: %w", err)
+	}
+	return loc, nil
+}
+
+// zoneLocation returns the location for the given --zone value.
+func zoneLocation(zone string) (*time.Location, error) {
+	switch zone {
+	case "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	default:
+		return nil, fmt.Errorf("unknown zone %q, must be local or utc", zone)
+	}
+}
+
+// currentTime returns the time to calculate for from the --now and --time
+// values. If tstring is set, it's parsed as RFC3339 and takes precedence over
+// tnow, and the time stays in the zone of its offset unless zoneSet is true.
+// Otherwise, tnow is used as Unix seconds in loc.
+func currentTime(tnow int64, tstring string, loc *time.Location, zoneSet bool) (time.Time, error) {
+	if tstring == "" {
+		return time.Unix(tnow, 0).In(loc), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, tstring)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if zoneSet {
+		t = t.In(loc)
+	}
+
+	return t, nil
+}
+
+// estimateLongitude estimates the longitude from the timezone at now. --zone
+// only changes how times are interpreted and printed, so the machine's timezone
+// is used unless tzSet is true, in which case loc is the --tz timezone.
+func estimateLongitude(now time.Time, loc *time.Location, tzSet bool) float64 {
+	if !tzSet {
+		loc = time.Local
+	}
+	return solar.TimeLongitudeDST(now.In(loc), !noDSTAdj)
+}
+
+// isFlagSet returns true if the flag with the given name was explicitly set.
+func isFlagSet(name string) bool {
+	var set bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// calculate calculates the Results for the given time using the current
+// flags.
+func calculate(now time.Time) Results {
 	lo := solar.Temperature(lowTemp)
 	hi := solar.Temperature(highTemp)
-	temp, sun := solar.CalculateTemperature(time.Unix(tnow, 0), latitude, longitude, lo, hi)
+	temp, sun := solar.CalculateTemperature(now, latitude, longitude, lo, hi)
 
 	r := Results{
+		Version:     apiVer,
+		Time:        now,
 		Latitude:    latitude,
 		Longitude:   longitude,
 		Geocode:     geocodeResults,
 		Temperature: temp,
-		Sun: SunResults{
-			Dawn:      sun.Dawn,
-			Sunrise:   sun.Sunrise,
-			Sunset:    sun.Sunset,
-			Dusk:      sun.Dusk,
-			Condition: sun.Condition.String(),
-		},
+		Sun:         sunResults(sun),
 	}
 
-	if printJSON {
-		r.PrintJSON(os.Stdout)
-	} else {
-		r.PrintText(os.Stdout)
+	if graph {
+		r.Graph = solar.TemperatureSparkline(now, latitude, longitude, lo, hi, graphWidth)
+	}
+
+	if wp {
+		r.Whitepoint = &WhitepointResults{}
+		r.Whitepoint.R, r.Whitepoint.G, r.Whitepoint.B = solar.CalculateWhitepoint(temp)
+	}
+
+	if hex {
+		r.WhitepointHex = solar.WhitepointHex(temp)
+	}
+
+	if days > 1 {
+		// Every day is derived from now, so they all share the location that
+		// main resolved once instead of looking it up again per day.
+		suns := solar.CalculateSunRange(now, days, latitude, longitude)
+		r.Days = make([]SunResults, len(suns))
+
+		for i, sun := range suns {
+			r.Days[i] = sunResults(sun)
+			r.Days[i].Date = now.AddDate(0, 0, i).Format(dateFormat)
+		}
+	}
+
+	return r
+}
+
+func sunResults(sun solar.Sun) SunResults {
+	return SunResults{
+		Dawn:      sun.Dawn,
+		Sunrise:   sun.Sunrise,
+		Sunset:    sun.Sunset,
+		Dusk:      sun.Dusk,
+		Condition: sun.Condition.String(),
 	}
 }
 
+// printRepeat calls print with the current time once, then again on every
+// tick until either ctx is done or tick is closed.
+func printRepeat(ctx context.Context, tick <-chan time.Time, print func(time.Time)) {
+	print(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t, ok := <-tick:
+			if !ok {
+				return
+			}
+			print(t)
+		}
+	}
+}
+
+// printWatch calls print every time temps receives a temperature until temps is
+// closed.
+func printWatch(temps <-chan solar.Temperature, print func()) {
+	for range temps {
+		print()
+	}
+}
+
+// latestAPIVersion is the version of the current JSON output shape.
+//
+// Version 1 is the original shape, which only has the latitude, longitude,
+// geocode, temperature and sun fields. Version 2 adds the version, days and
+// graph fields, as well as the date of each sun. Version 3 adds the whitepoint
+// and whitepoint_hex fields.
+const latestAPIVersion = 3
+
+// Results is the output of the program. The JSON field names are stable, and
+// new fields are only ever added to the end of the struct along with a bump of
+// latestAPIVersion, so the output keeps the same order.
 type Results struct {
-	Latitude    float64           `json:"latitude"`
-	Longitude   float64           `json:"longitude"`
-	Geocode     *GeocodeResults   `json:"geocode,omitempty"`
-	Temperature solar.Temperature `json:"temperature"`
-	Sun         SunResults        `json:"sun"`
+	Version       int                `json:"version"`
+	Latitude      float64            `json:"latitude"`
+	Longitude     float64            `json:"longitude"`
+	Geocode       *GeocodeResults    `json:"geocode,omitempty"`
+	Temperature   solar.Temperature  `json:"temperature"`
+	Sun           SunResults         `json:"sun"`
+	Days          []SunResults       `json:"days,omitempty"`
+	Graph         string             `json:"graph,omitempty"`
+	Whitepoint    *WhitepointResults `json:"whitepoint,omitempty"`
+	WhitepointHex string             `json:"whitepoint_hex,omitempty"`
+
+	// Time is the time that the results are calculated for. It is only used
+	// by StatusLine and isn't part of the JSON output.
+	Time time.Time `json:"-"`
+}
+
+// WhitepointResults is the RGB whitepoint of the color temperature, with each
+// channel within [0.0, 1.0].
+type WhitepointResults struct {
+	R float64 `json:"r"`
+	G float64 `json:"g"`
+	B float64 `json:"b"`
 }
 
+// graphWidth is the width of the -graph sparkline, one character for every
+// half an hour.
+const graphWidth = 48
+
+const dateFormat = "2006-01-02"
+
 type SunResults struct {
+	Date      string    `json:"date,omitempty"`
 	Dawn      time.Time `json:"dawn,omitempty"`
 	Sunrise   time.Time `json:"sunrise,omitempty"`
 	Sunset    time.Time `json:"sunset,omitempty"`
@@ -115,13 +420,18 @@ type GeocodeResults struct {
 	Country string `json:"country,omitempty"`
 }
 
-func (r Results) PrintText(w io.Writer) {
+// PrintText writes the results in a human-readable format into w.
+func (r Results) PrintText(w io.Writer) error {
+	// Buffer the output so that it is written all at once, and so that only a
+	// single write error needs checking.
+	var buf bytes.Buffer
+
 	printlnf := func(f string, v ...interface{}) {
-		fmt.Fprintln(w, fmt.Sprintf(f, v...))
+		fmt.Fprintln(&buf, fmt.Sprintf(f, v...))
 	}
 	printTime := func(name string, t time.Time) {
 		if !t.IsZero() {
-			fmt.Fprintf(w, "%s: %s\n", name, t.Format(tformat))
+			fmt.Fprintf(&buf, "%s: %s\n", name, t.Format(tformat))
 		}
 	}
 
@@ -130,20 +440,167 @@ func (r Results) PrintText(w io.Writer) {
 	if r.Geocode != nil {
 		printlnf("location: %s, %s", r.Geocode.City, r.Geocode.Country)
 	}
-	printlnf("sun condition: %s", r.Sun.Condition)
-	printTime("dawn time", r.Sun.Dawn)
-	printTime("sunrise time", r.Sun.Sunrise)
-	printTime("sunset time", r.Sun.Sunset)
-	printTime("dusk time", r.Sun.Dusk)
+	printSun := func(sun SunResults) {
+		if sun.Date != "" {
+			printlnf("date: %s", sun.Date)
+		}
+		printlnf("sun condition: %s", sun.Condition)
+		printTime("dawn time", sun.Dawn)
+		printTime("sunrise time", sun.Sunrise)
+		printTime("sunset time", sun.Sunset)
+		printTime("dusk time", sun.Dusk)
+	}
+
+	if len(r.Days) > 0 {
+		for _, sun := range r.Days {
+			printSun(sun)
+		}
+	} else {
+		printSun(r.Sun)
+	}
 	printlnf("color temperature: %.0fK", r.Temperature)
+	if r.Graph != "" {
+		printlnf("temperature graph: %s", r.Graph)
+	}
+	if wp := r.Whitepoint; wp != nil {
+		printlnf("whitepoint: %.3f %.3f %.3f", wp.R, wp.G, wp.B)
+	}
+	if r.WhitepointHex != "" {
+		printlnf("whitepoint hex: %s", r.WhitepointHex)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// StatusLine formats the results into a short line for status bars, such as
+// "☀ 6500K · set 16:18". The glyph shows whether it's day (☀), twilight (◐) or
+// night (☾), and the line ends with the next sun event, if there's one today.
+func (r Results) StatusLine() string {
+	glyph := "☾"
+
+	cond, _ := solar.ParseSunCondition(r.Sun.Condition)
+	switch {
+	case cond == solar.MidnightSun && r.Sun.Sunrise.IsZero():
+		glyph = "☀"
+	case cond == solar.PolarNightSun:
+		glyph = "☾"
+	case between(r.Time, r.Sun.Sunrise, r.Sun.Sunset):
+		glyph = "☀"
+	case between(r.Time, r.Sun.Dawn, r.Sun.Sunrise), between(r.Time, r.Sun.Sunset, r.Sun.Dusk):
+		glyph = "◐"
+	}
+
+	line := fmt.Sprintf("%s %.0fK", glyph, r.Temperature)
+
+	events := []struct {
+		name string
+		time time.Time
+	}{
+		{"dawn", r.Sun.Dawn},
+		{"rise", r.Sun.Sunrise},
+		{"set", r.Sun.Sunset},
+		{"dusk", r.Sun.Dusk},
+	}
+
+	for _, event := range events {
+		if !event.time.IsZero() && event.time.After(r.Time) {
+			line += fmt.Sprintf(" · %s %s", event.name, event.time.Format("15:04"))
+			break
+		}
+	}
+
+	return line
+}
+
+// Redshift's bounds on the color temperature that it accepts.
+const (
+	redshiftMinTemp = 1000
+	redshiftMaxTemp = 25000
+)
+
+// RedshiftTemperature returns the color temperature rounded to whole Kelvins
+// and clamped to what redshift accepts, so it can be given to redshift -O.
+func (r Results) RedshiftTemperature() int {
+	temp := int(math.Round(float64(r.Temperature)))
+	if temp < redshiftMinTemp {
+		return redshiftMinTemp
+	}
+	if temp > redshiftMaxTemp {
+		return redshiftMaxTemp
+	}
+	return temp
+}
+
+// between returns true if t is within [start, end). False is returned if
+// either start or end is zero.
+func between(t, start, end time.Time) bool {
+	return !start.IsZero() && !end.IsZero() && !t.Before(start) && t.Before(end)
+}
+
+// resultsV1 is the version 1 shape of Results.
+type resultsV1 struct {
+	Latitude    float64           `json:"latitude"`
+	Longitude   float64           `json:"longitude"`
+	Geocode     *GeocodeResults   `json:"geocode,omitempty"`
+	Temperature solar.Temperature `json:"temperature"`
+	Sun         sunResultsV1      `json:"sun"`
 }
 
-func (r Results) PrintJSON(w io.Writer) {
+// sunResultsV1 is the version 1 shape of SunResults.
+type sunResultsV1 struct {
+	Dawn      time.Time `json:"dawn,omitempty"`
+	Sunrise   time.Time `json:"sunrise,omitempty"`
+	Sunset    time.Time `json:"sunset,omitempty"`
+	Dusk      time.Time `json:"dusk,omitempty"`
+	Condition string    `json:"condition"`
+}
+
+// PrintJSON writes the results as indented JSON into w in the shape of
+// r.Version.
+func (r Results) PrintJSON(w io.Writer) error {
 	e := json.NewEncoder(w)
 	e.SetIndent("", "  ")
-	if err := e.Encode(r); err != nil {
-		log.Panicln("cannot encode JSON:", err)
+	if err := e.Encode(r.versioned()); err != nil {
+		return fmt.Errorf("cannot encode JSON: %w", err)
+	}
+	return nil
+}
+
+// PrintJSONLine is like PrintJSON, except the JSON is written as a single line,
+// so that repeated results form JSON Lines.
+func (r Results) PrintJSONLine(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(r.versioned()); err != nil {
+		return fmt.Errorf("cannot encode JSON: %w", err)
+	}
+	return nil
+}
+
+// versioned returns the value to encode as JSON for the results in the shape of
+// r.Version.
+func (r Results) versioned() interface{} {
+	switch r.Version {
+	case 1:
+		return resultsV1{
+			Latitude:    r.Latitude,
+			Longitude:   r.Longitude,
+			Geocode:     r.Geocode,
+			Temperature: r.Temperature,
+			Sun: sunResultsV1{
+				Dawn:      r.Sun.Dawn,
+				Sunrise:   r.Sun.Sunrise,
+				Sunset:    r.Sun.Sunset,
+				Dusk:      r.Sun.Dusk,
+				Condition: r.Sun.Condition,
+			},
+		}
+	case 2:
+		// Version 2 is the same shape without the whitepoint fields, which are
+		// omitted when empty.
+		r.Whitepoint = nil
+		r.WhitepointHex = ""
 	}
+	return r
 }
 
 func myIP() (string, error) {
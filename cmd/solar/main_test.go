@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/solar"
+
+	_ "time/tzdata"
+)
+
+var losAngeles *time.Location
+
+func init() {
+	z, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		panic("cannot load embedded America/Los_Angeles: " + err.Error())
+	}
+
+	losAngeles = z
+}
+
+// useLosAngeles sets the latitude and longitude flags to Los Angeles until the
+// test finishes.
+func useLosAngeles(tb testing.TB) {
+	lat, long := latitude, longitude
+	tb.Cleanup(func() { latitude, longitude = lat, long })
+	latitude, longitude = 34.1, -118.2
+}
+
+func TestPrintRepeat(t *testing.T) {
+	useLosAngeles(t)
+
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+
+	var buf bytes.Buffer
+	go func() {
+		printRepeat(context.Background(), tick, func(now time.Time) {
+			calculate(now).PrintText(&buf)
+		})
+		close(done)
+	}()
+
+	tick <- time.Unix(1636333967, 0)
+	tick <- time.Unix(1636333967+60, 0)
+	close(tick)
+	<-done
+
+	if n := strings.Count(buf.String(), "color temperature:"); n != 3 {
+		t.Fatalf("expected 3 prints, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestPrintRepeatCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var n int
+	printRepeat(ctx, make(chan time.Time), func(time.Time) { n++ })
+
+	if n != 1 {
+		t.Fatalf("expected 1 print after cancellation, got %d", n)
+	}
+}
+
+func TestDays(t *testing.T) {
+	useLosAngeles(t)
+
+	days = 2
+	defer func() { days = 1 }()
+
+	var buf bytes.Buffer
+	calculate(time.Unix(1636333967, 0).UTC()).PrintText(&buf)
+
+	var dates []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "date: ") {
+			dates = append(dates, strings.TrimPrefix(line, "date: "))
+		}
+	}
+
+	if len(dates) != 2 || dates[0] != "2021-11-08" || dates[1] != "2021-11-09" {
+		t.Fatalf("expected 2 consecutive days, got %q in:\n%s", dates, buf.String())
+	}
+
+	if n := strings.Count(buf.String(), "sun condition:"); n != 2 {
+		t.Fatalf("expected 2 sun blocks, got %d", n)
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	useLosAngeles(t)
+
+	// 2021-11-07 12:00:00 PST.
+	now := time.Date(2021, time.November, 7, 20, 0, 0, 0, time.UTC).In(losAngeles)
+	r := calculate(now)
+
+	line := r.StatusLine()
+	t.Log("status:", line)
+
+	if !strings.HasPrefix(line, "☀ 6500K") {
+		t.Errorf("expected daytime at 6500K, got %q", line)
+	}
+
+	if set := "set " + r.Sun.Sunset.Format("15:04"); !strings.HasSuffix(line, set) {
+		t.Errorf("expected the next event %q, got %q", set, line)
+	}
+}
+
+func TestZone(t *testing.T) {
+	useLosAngeles(t)
+
+	const tnow = 1636333967
+
+	for _, zone := range []string{"local", "utc"} {
+		t.Run(zone, func(t *testing.T) {
+			loc, err := zoneLocation(zone)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			r := calculate(time.Unix(tnow, 0).In(loc))
+			if r.Time.Unix() != tnow {
+				t.Errorf("expected the same instant, got %s", r.Time)
+			}
+
+			for _, event := range []time.Time{r.Time, r.Sun.Dawn, r.Sun.Sunrise, r.Sun.Sunset, r.Sun.Dusk} {
+				if event.Location() != loc {
+					t.Errorf("%s is not in the %s zone", event, zone)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := zoneLocation("mars"); err == nil {
+			t.Error("expected an error for an unknown zone")
+		}
+	})
+}
+
+func TestRedshiftTemperature(t *testing.T) {
+	useLosAngeles(t)
+
+	now := time.Date(2021, time.November, 7, 20, 0, 0, 0, time.UTC).In(losAngeles)
+	if temp := calculate(now).RedshiftTemperature(); temp != 6500 {
+		t.Errorf("expected 6500K during the day, got %d", temp)
+	}
+
+	for _, temp := range []float64{10, 50000} {
+		v := Results{Temperature: solar.Temperature(temp)}.RedshiftTemperature()
+		if v < redshiftMinTemp || v > redshiftMaxTemp {
+			t.Errorf("%gK: expected the temperature to be clamped, got %d", temp, v)
+		}
+	}
+}
+
+func TestDaysZone(t *testing.T) {
+	useLosAngeles(t)
+
+	days = 7
+	defer func() { days = 1 }()
+
+	r := calculate(time.Unix(1636333967, 0).In(losAngeles))
+	if len(r.Days) != days {
+		t.Fatalf("expected %d days, got %d", days, len(r.Days))
+	}
+
+	for _, day := range r.Days {
+		for _, event := range []time.Time{day.Dawn, day.Sunrise, day.Sunset, day.Dusk} {
+			if event.Location() != losAngeles {
+				t.Errorf("%s: %s is not in America/Los_Angeles", day.Date, event)
+			}
+		}
+	}
+}
+
+func BenchmarkCalculateDays(b *testing.B) {
+	useLosAngeles(b)
+
+	days = 365
+	defer func() { days = 1 }()
+
+	now := time.Unix(1636333967, 0).In(losAngeles)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calculate(now)
+	}
+}
+
+// errWriter is an io.Writer that always fails.
+type errWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (errWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestPrintError(t *testing.T) {
+	useLosAngeles(t)
+
+	r := calculate(time.Unix(1636333967, 0).In(losAngeles))
+
+	if err := r.PrintText(errWriter{}); !errors.Is(err, errWrite) {
+		t.Errorf("PrintText: expected the write error, got %v", err)
+	}
+	if err := r.PrintJSON(errWriter{}); !errors.Is(err, errWrite) {
+		t.Errorf("PrintJSON: expected the write error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.PrintJSON(&buf); err != nil {
+		t.Errorf("PrintJSON: unexpected error: %v", err)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	useLosAngeles(t)
+
+	graph = true
+	defer func() { graph = false }()
+
+	var buf bytes.Buffer
+	calculate(time.Unix(1636333967, 0).In(losAngeles)).PrintText(&buf)
+
+	if !strings.Contains(buf.String(), "temperature graph: ▁") {
+		t.Errorf("expected a temperature graph, got:\n%s", buf.String())
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	useLosAngeles(t)
+
+	days = 2
+	defer func() { days = 1 }()
+
+	decode := func(t *testing.T) map[string]interface{} {
+		var buf bytes.Buffer
+		if err := calculate(time.Unix(1636333967, 0).In(losAngeles)).PrintJSON(&buf); err != nil {
+			t.Fatal("cannot print JSON:", err)
+		}
+
+		var v map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+			t.Fatal("cannot decode JSON:", err)
+		}
+		return v
+	}
+
+	t.Run("latest", func(t *testing.T) {
+		v := decode(t)
+		if version, ok := v["version"].(float64); !ok || version != latestAPIVersion {
+			t.Errorf("expected version %d, got %v", latestAPIVersion, v["version"])
+		}
+		for _, field := range []string{"days"} {
+			if _, ok := v[field]; !ok {
+				t.Errorf("expected the %q field", field)
+			}
+		}
+		if _, ok := v["time"]; ok {
+			t.Error("unexpected time field")
+		}
+	})
+
+	t.Run("v1", func(t *testing.T) {
+		apiVer = 1
+		defer func() { apiVer = latestAPIVersion }()
+
+		v := decode(t)
+		for _, field := range []string{"version", "days", "graph"} {
+			if _, ok := v[field]; ok {
+				t.Errorf("unexpected %q field in version 1", field)
+			}
+		}
+		for _, field := range []string{"latitude", "longitude", "temperature", "sun"} {
+			if _, ok := v[field]; !ok {
+				t.Errorf("expected the %q field in version 1", field)
+			}
+		}
+		if sun := v["sun"].(map[string]interface{}); sun["date"] != nil {
+			t.Errorf("unexpected sun date in version 1: %v", sun["date"])
+		}
+	})
+}
+
+func TestPrintWatch(t *testing.T) {
+	useLosAngeles(t)
+
+	temps := make(chan solar.Temperature, 2)
+	temps <- 4000
+	temps <- 4100
+	close(temps)
+
+	now := time.Unix(1636333967, 0).In(losAngeles)
+
+	var buf bytes.Buffer
+	printWatch(temps, func() {
+		if err := calculate(now).PrintJSONLine(&buf); err != nil {
+			t.Fatal("cannot print JSON line:", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	for i, line := range lines {
+		var r Results
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("line %d: cannot decode JSON: %v", i, err)
+			continue
+		}
+		if r.Sun.Condition != solar.NormalSun.String() {
+			t.Errorf("line %d: expected a normal sun, got %q", i, r.Sun.Condition)
+		}
+	}
+}
+
+func TestWhitepoint(t *testing.T) {
+	useLosAngeles(t)
+
+	wp, hex = true, true
+	defer func() { wp, hex = false, false }()
+
+	// 2021-11-07 12:00:00 PST, during the day at 6500K.
+	r := calculate(time.Date(2021, time.November, 7, 20, 0, 0, 0, time.UTC).In(losAngeles))
+
+	var buf bytes.Buffer
+	r.PrintText(&buf)
+
+	for _, line := range []string{"whitepoint: 1.000 1.000 1.000\n", "whitepoint hex: #ffffff\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q, got:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := r.PrintJSON(&buf); err != nil {
+		t.Fatal("cannot print JSON:", err)
+	}
+
+	var v struct {
+		Whitepoint    map[string]float64 `json:"whitepoint"`
+		WhitepointHex string             `json:"whitepoint_hex"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal("cannot decode JSON:", err)
+	}
+	for _, field := range []string{"r", "g", "b"} {
+		if v.Whitepoint[field] != 1 {
+			t.Errorf("expected the whitepoint %q field to be 1, got %v", field, v.Whitepoint)
+		}
+	}
+	if v.WhitepointHex != "#ffffff" {
+		t.Errorf("expected the whitepoint_hex field to be #ffffff, got %q", v.WhitepointHex)
+	}
+
+	t.Run("v2", func(t *testing.T) {
+		apiVer = 2
+		defer func() { apiVer = latestAPIVersion }()
+
+		var buf bytes.Buffer
+		r := calculate(time.Date(2021, time.November, 7, 20, 0, 0, 0, time.UTC).In(losAngeles))
+		if err := r.PrintJSON(&buf); err != nil {
+			t.Fatal("cannot print JSON:", err)
+		}
+
+		var v map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+			t.Fatal("cannot decode JSON:", err)
+		}
+		for _, field := range []string{"whitepoint", "whitepoint_hex"} {
+			if _, ok := v[field]; ok {
+				t.Errorf("unexpected %q field in version 2", field)
+			}
+		}
+		if version, ok := v["version"].(float64); !ok || version != 2 {
+			t.Errorf("expected version 2, got %v", v["version"])
+		}
+	})
+}
+
+func TestCurrentTime(t *testing.T) {
+	const tnow = 1636333967
+
+	now, err := currentTime(tnow, "", losAngeles, false)
+	if err != nil || now.Unix() != tnow || now.Location() != losAngeles {
+		t.Errorf("expected --now in America/Los_Angeles, got %s, %v", now, err)
+	}
+
+	now, err = currentTime(0, "2021-11-07T17:12:47-08:00", time.UTC, false)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if now.Unix() != tnow {
+		t.Errorf("expected --time to override --now, got %d", now.Unix())
+	}
+	if _, offset := now.Zone(); offset != -8*60*60 {
+		t.Errorf("expected the -08:00 offset of --time, got %d", offset)
+	}
+
+	now, err = currentTime(0, "2021-11-07T17:12:47-08:00", time.UTC, true)
+	if err != nil || now.Unix() != tnow || now.Location() != time.UTC {
+		t.Errorf("expected an explicit --zone to override the offset, got %s, %v", now, err)
+	}
+
+	if _, err := currentTime(0, "2021-11-07 17:12:47", time.UTC, false); err == nil {
+		t.Error("expected an error for a time that isn't RFC3339")
+	}
+}
+
+func TestFlagLocation(t *testing.T) {
+	loc, err := flagLocation("utc", "America/New_York")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if loc.String() != "America/New_York" {
+		t.Errorf("expected --tz to override --zone, got %s", loc)
+	}
+
+	if loc, err := flagLocation("utc", ""); err != nil || loc != time.UTC {
+		t.Errorf("expected --zone without --tz, got %v, %v", loc, err)
+	}
+
+	if _, err := flagLocation("local", "Nowhere/Else"); err == nil || !strings.Contains(err.Error(), "--tz") {
+		t.Errorf("expected an invalid --tz error, got %v", err)
+	}
+	if _, err := flagLocation("mars", ""); err == nil || !strings.Contains(err.Error(), "--zone") {
+		t.Errorf("expected an invalid --zone error, got %v", err)
+	}
+
+	// Explicit coordinates are kept, and only the clock changes.
+	useLosAngeles(t)
+
+	now, err := currentTime(1636333967, "", loc, true)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	r := calculate(now)
+	if r.Latitude != 34.1 || r.Longitude != -118.2 {
+		t.Errorf("expected the coordinates to be kept, got %g, %g", r.Latitude, r.Longitude)
+	}
+	if r.Sun.Sunset.Location() != loc {
+		t.Errorf("expected the sunset in %s, got %s", loc, r.Sun.Sunset.Location())
+	}
+}
+
+func TestEstimateLongitude(t *testing.T) {
+	local := time.Local
+	time.Local = losAngeles
+	defer func() { time.Local = local }()
+
+	now := time.Unix(1636333967, 0)
+	expect := solar.TimeLongitudeDST(now.In(losAngeles), true)
+
+	for _, zone := range []string{"local", "utc"} {
+		loc, err := zoneLocation(zone)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if long := estimateLongitude(now.In(loc), loc, false); long != expect {
+			t.Errorf("%s: expected the longitude of the machine's timezone %g, got %g", zone, expect, long)
+		}
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal("cannot load America/New_York:", err)
+	}
+	if long := estimateLongitude(now.In(newYork), newYork, true); long != -75 {
+		t.Errorf("expected --tz to estimate -75, got %g", long)
+	}
+}
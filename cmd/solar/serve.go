@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/solar"
+)
+
+// newServeMux creates the handler for -serve. now is called once per request
+// for the time to calculate the conditions at.
+func newServeMux(now func() time.Time) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conditions", func(w http.ResponseWriter, r *http.Request) {
+		serveConditions(w, r, now())
+	})
+	return mux
+}
+
+// Conditions is the current conditions at a single location.
+type Conditions struct {
+	Latitude    float64           `json:"latitude"`
+	Longitude   float64           `json:"longitude"`
+	Temperature solar.Temperature `json:"temperature"`
+	Sun         SunResults        `json:"sun"`
+}
+
+// serveConditions serves the conditions of the named locations given in the
+// locations query, formatted as "name:lat,long;name:lat,long", as a JSON
+// object keyed by the names.
+func serveConditions(w http.ResponseWriter, r *http.Request, now time.Time) {
+	locations, err := parseLocations(r.URL.Query().Get("locations"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lo := solar.Temperature(lowTemp)
+	hi := solar.Temperature(highTemp)
+
+	// Locations that share the same coordinates share the same calculator, so
+	// they're only calculated once per request.
+	calculators := make(map[[2]float64]*solar.SunCalculator, len(locations))
+	conditions := make(map[string]Conditions, len(locations))
+
+	for name, coords := range locations {
+		calc, ok := calculators[coords]
+		if !ok {
+			calc = solar.NewSunCalculator(coords[0], coords[1])
+			calculators[coords] = calc
+		}
+
+		temp, sun := calc.Temperature(now, lo, hi)
+		conditions[name] = Conditions{
+			Latitude:    coords[0],
+			Longitude:   coords[1],
+			Temperature: temp,
+			Sun:         sunResults(sun),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(conditions); err != nil {
+		// The response has already started, so the client can only be dropped.
+		log.Println("cannot write conditions:", err)
+	}
+}
+
+// parseLocations parses the locations query of /conditions into a map of names
+// to latitude and longitude pairs.
+func parseLocations(query string) (map[string][2]float64, error) {
+	if query == "" {
+		return nil, fmt.Errorf("missing locations")
+	}
+
+	locations := make(map[string][2]float64)
+
+	for _, location := range strings.Split(query, ";") {
+		parts := strings.SplitN(location, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid location %q, must be name:lat,long", location)
+		}
+		name := parts[0]
+
+		coords := strings.Split(parts[1], ",")
+		if len(coords) != 2 {
+			return nil, fmt.Errorf("invalid coordinates %q for %s, must be lat,long", parts[1], name)
+		}
+		latStr, longStr := coords[0], coords[1]
+
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("invalid latitude %q for %s", latStr, name)
+		}
+
+		long, err := strconv.ParseFloat(longStr, 64)
+		if err != nil || long < -180 || long > 180 {
+			return nil, fmt.Errorf("invalid longitude %q for %s", longStr, name)
+		}
+
+		if _, dup := locations[name]; dup {
+			return nil, fmt.Errorf("duplicate location %q", name)
+		}
+
+		locations[name] = [2]float64{lat, long}
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/solar"
+)
+
+func TestServeConditions(t *testing.T) {
+	now := time.Date(2021, time.November, 8, 20, 0, 0, 0, time.UTC)
+
+	srv := httptest.NewServer(newServeMux(func() time.Time { return now }))
+	defer srv.Close()
+
+	q := url.Values{"locations": {"la:34.1,-118.2;tromso:69.6,18.9"}}
+
+	r, err := http.Get(srv.URL + "/conditions?" + q.Encode())
+	if err != nil {
+		t.Fatal("cannot GET /conditions:", err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", r.StatusCode)
+	}
+
+	var conditions map[string]Conditions
+	if err := json.NewDecoder(r.Body).Decode(&conditions); err != nil {
+		t.Fatal("cannot decode JSON:", err)
+	}
+
+	if len(conditions) != 2 {
+		t.Fatalf("expected 2 locations, got %v", conditions)
+	}
+
+	for name, coords := range map[string][2]float64{"la": {34.1, -118.2}, "tromso": {69.6, 18.9}} {
+		c, ok := conditions[name]
+		if !ok {
+			t.Errorf("missing location %q", name)
+			continue
+		}
+
+		temp, sun := solar.CalculateTemperature(now, coords[0], coords[1],
+			solar.Temperature(lowTemp), solar.Temperature(highTemp))
+
+		if c.Latitude != coords[0] || c.Longitude != coords[1] {
+			t.Errorf("%s: unexpected coordinates %g,%g", name, c.Latitude, c.Longitude)
+		}
+		if c.Temperature != temp {
+			t.Errorf("%s: expected %.0fK, got %.0fK", name, temp, c.Temperature)
+		}
+		if c.Sun.Condition != sun.Condition.String() || !c.Sun.Sunrise.Equal(sun.Sunrise) {
+			t.Errorf("%s: expected sun %v, got %+v", name, sun, c.Sun)
+		}
+	}
+
+	if conditions["la"].Sun == conditions["tromso"].Sun {
+		t.Error("expected the locations to have different sun data")
+	}
+}
+
+func TestServeConditionsInvalid(t *testing.T) {
+	mux := newServeMux(time.Now)
+
+	for _, query := range []string{"", "la", "la:34.1", "la:91,0", "la:0,0;la:1,1"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/conditions?"+url.Values{"locations": {query}}.Encode(), nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/diamondburned/solar"
+)
+
+// yearMain is the main function of the year subcommand, which prints the sun
+// data of every day in a year as a JSON array.
+func yearMain(args []string) error {
+	var (
+		year    = time.Now().Year()
+		lat     = 0.0
+		long    = 0.0
+		zone    = "local"
+		longSet = false
+	)
+
+	fs := flag.NewFlagSet("year", flag.ExitOnError)
+	fs.IntVar(&year, "year", year, "year to print the sun data for")
+	fs.Var(latitudeValue(&lat), "lat", "latitude in decimal degrees or DMS")
+	fs.Var(longitudeValue(&long), "long", "longitude in decimal degrees or DMS, estimated from the timezone if not set")
+	fs.StringVar(&zone, "zone", zone, "timezone to print times in: local or utc")
+	fs.Parse(args)
+
+	fs.Visit(func(f *flag.Flag) { longSet = longSet || f.Name == "long" })
+
+	loc, err := zoneLocation(zone)
+	if err != nil {
+		return fmt.Errorf("invalid --zone: %w", err)
+	}
+
+	if !longSet {
+		long = solar.TimeLongitudeDST(time.Date(year, time.January, 1, 0, 0, 0, 0, loc), true)
+	}
+
+	return printYear(os.Stdout, yearResults(year, lat, long, loc))
+}
+
+// yearResults calculates the sun data for every day of the given year in the
+// given location.
+func yearResults(year int, lat, long float64, loc *time.Location) []SunResults {
+	start := time.Date(year, time.January, 1, 12, 0, 0, 0, loc)
+	days := time.Date(year, time.December, 31, 12, 0, 0, 0, loc).YearDay()
+
+	suns := solar.CalculateSunRange(start, days, lat, long)
+
+	results := make([]SunResults, len(suns))
+	for i, sun := range suns {
+		results[i] = sunResults(sun)
+		results[i].Date = start.AddDate(0, 0, i).Format(dateFormat)
+	}
+
+	return results
+}
+
+func printYear(w io.Writer, results []SunResults) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(results); err != nil {
+		return fmt.Errorf("cannot encode JSON: %w", err)
+	}
+	return nil
+}
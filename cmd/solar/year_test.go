@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestYearResults(t *testing.T) {
+	for year, expect := range map[int]int{2021: 365, 2024: 366} {
+		results := yearResults(year, 34.1, -118.2, losAngeles)
+		if len(results) != expect {
+			t.Errorf("%d: expected %d days, got %d", year, expect, len(results))
+			continue
+		}
+
+		day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for _, r := range results {
+			if expect := day.Format(dateFormat); r.Date != expect {
+				t.Fatalf("%d: expected %s, got %s", year, expect, r.Date)
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+}
+
+func TestPrintYear(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printYear(&buf, yearResults(2021, 34.1, -118.2, losAngeles)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var results []SunResults
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatal("cannot decode the printed JSON:", err)
+	}
+
+	if len(results) != 365 || results[0].Date != "2021-01-01" || results[364].Date != "2021-12-31" {
+		t.Errorf("unexpected printed year with %d days", len(results))
+	}
+}
@@ -2,4 +2,7 @@ module github.com/diamondburned/solar
 
 go 1.17
 
-require github.com/go-test/deep v1.0.8
+require (
+	github.com/go-test/deep v1.0.8
+	github.com/godbus/dbus/v5 v5.1.0
+)
@@ -0,0 +1,474 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// SunElevation calculates the elevation (altitude) of the sun in degrees at the
+// given time instant and location. The given latitude and longitude must be in
+// degrees. The elevation is negative when the sun is below the horizon.
+//
+// The elevation is calculated using the same date and longitude handling as
+// CalculateSun, so the sun is at the twilight elevations exactly when
+// CalculateSun says it is.
+func SunElevation(t time.Time, lat, long float64) float64 {
+	altitude, _ := SunPosition(t, lat, long)
+	return altitude
+}
+
+// SunPosition calculates the position of the sun in the sky at the given time
+// instant and location. The altitude is the same as SunElevation, and the
+// azimuth is in degrees within [0, 360), clockwise from north.
+func SunPosition(t time.Time, lat, long float64) (altitude, azimuth float64) {
+	day := newSunDay(t, lat, long)
+	ha := day.hourAngleAt(t)
+	return Degrees(day.elevation(ha)), day.azimuth(ha)
+}
+
+// azimuth returns the azimuth of the sun in degrees within [0, 360) at the
+// given hour angle.
+func (d sunDay) azimuth(hourAngle float64) float64 {
+	// The hour angle is positive in the morning, when the sun is in the east.
+	az := math.Atan2(
+		math.Sin(hourAngle),
+		math.Tan(d.decl)*math.Cos(d.lat)-math.Sin(d.lat)*math.Cos(hourAngle),
+	)
+
+	return math.Mod(Degrees(az)+360, 360)
+}
+
+// MaxSolarElevation calculates the highest elevation of the sun in degrees on
+// the day of the given date, which it reaches at solar noon.
+func MaxSolarElevation(date time.Time, lat, long float64) float64 {
+	return Degrees(newSunDay(date, lat, long).elevation(0))
+}
+
+// SunPathPoint is a position of the sun in the sky, both in degrees. It is the
+// same as what SunPosition returns.
+type SunPathPoint struct {
+	Azimuth  float64
+	Altitude float64
+}
+
+// SunAltitudesForDay samples the position of the sun every step from midnight
+// of the given date in its location until the next midnight, tracing the path
+// of the sun across the sky on that day. Points below the horizon are
+// included. This can be compared against a measured skyline to find when the
+// sun clears it. Nil is returned if step is not positive.
+func SunAltitudesForDay(date time.Time, lat, long float64, step time.Duration) []SunPathPoint {
+	if step <= 0 {
+		return nil
+	}
+
+	day := newSunDay(date, lat, long)
+
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+
+	points := make([]SunPathPoint, 0, int(end.Sub(start)/step)+1)
+	for t := start; t.Before(end); t = t.Add(step) {
+		ha := day.hourAngleAt(t)
+		points = append(points, SunPathPoint{
+			Azimuth:  day.azimuth(ha),
+			Altitude: Degrees(day.elevation(ha)),
+		})
+	}
+
+	return points
+}
+
+// hourAngleAt returns the hour angle of the sun at the given time instant. It
+// is the inverse of timeAt.
+func (d sunDay) hourAngleAt(t time.Time) float64 {
+	secs := t.Sub(d.start).Seconds()
+	return (4*math.Pi - d.eqtime - Radians(secs)/60) / 4
+}
+
+// elevation returns the elevation of the sun in radians at the given hour
+// angle.
+func (d sunDay) elevation(hourAngle float64) float64 {
+	// https://www.esrl.noaa.gov/gmd/grad/solcalc/solareqns.PDF
+	return math.Asin(0 +
+		math.Sin(d.lat)*math.Sin(d.decl) +
+		math.Cos(d.lat)*math.Cos(d.decl)*math.Cos(hourAngle))
+}
+
+// noon returns the time instant of solar noon.
+func (d sunDay) noon() time.Time {
+	return d.timeAt(0)
+}
+
+// calculateSunIterative calculates the same values as CalculateSun, except it
+// does so by searching for the times at which the sun crosses each elevation
+// instead of solving for the hour angle. It is much slower and only exists to
+// cross-check CalculateSun.
+func calculateSunIterative(t time.Time, lat, long float64) Sun {
+	day := newSunDay(t, lat, long)
+	noon := day.noon()
+
+	elevationAt := func(t time.Time) float64 {
+		return day.elevation(day.hourAngleAt(t))
+	}
+
+	// crossing searches for the time between from and to that the sun crosses
+	// the given zenith angle. The sun must be on opposite sides of the angle at
+	// from and to, otherwise a zero time is returned.
+	crossing := func(from, to time.Time, zenith float64) time.Time {
+		elevation := math.Pi/2 - zenith
+
+		fromAbove := elevationAt(from) > elevation
+		if fromAbove == (elevationAt(to) > elevation) {
+			return time.Time{}
+		}
+
+		for to.Sub(from) > time.Millisecond {
+			mid := from.Add(to.Sub(from) / 2)
+			if (elevationAt(mid) > elevation) == fromAbove {
+				from = mid
+			} else {
+				to = mid
+			}
+		}
+
+		return from
+	}
+
+	morning := noon.Add(-12 * time.Hour)
+	evening := noon.Add(+12 * time.Hour)
+
+	sun := Sun{
+		Dawn:    crossing(morning, noon, startTwilight),
+		Sunrise: crossing(morning, noon, endTwilight),
+		Sunset:  crossing(noon, evening, endTwilight),
+		Dusk:    crossing(noon, evening, startTwilight),
+	}
+
+	switch {
+	case sun.Dawn.IsZero() || sun.Sunrise.IsZero() || sun.Sunset.IsZero() || sun.Dusk.IsZero():
+		// The sun is either always above or always below one of the angles.
+		// It's polar night if the sun doesn't get high enough to rise at noon.
+		if elevationAt(noon) < math.Pi/2-endTwilight {
+			sun.Condition = PolarNightSun
+		} else {
+			sun.Condition = MidnightSun
+		}
+	default:
+		sun.Condition = NormalSun
+	}
+
+	return sun
+}
+
+// NextTimeAtElevation searches for the first time instant after the given one
+// that the sun is at the given elevation in degrees, either while rising or
+// setting. The search goes forward day by day, so it may return a time instant
+// in a later season if the sun doesn't get that high today. False is returned
+// if the sun doesn't reach the elevation within a year.
+func NextTimeAtElevation(after time.Time, lat, long, elevationDeg float64) (time.Time, bool) {
+	zenith := Radians(90 - elevationDeg)
+
+	// Start from yesterday like NextTransitionTime, since its evening can still
+	// be ahead of after if the solar day starts late in after's timezone.
+	for i := -1; i <= 366; i++ {
+		day := newSunDay(after.AddDate(0, 0, i), lat, long)
+
+		ha := day.hourAngle(zenith)
+		if math.IsNaN(ha) {
+			continue
+		}
+
+		for _, t := range []time.Time{day.timeAt(+math.Abs(ha)), day.timeAt(-math.Abs(ha))} {
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// julianDate returns the Julian date of the given time instant.
+func julianDate(t time.Time) float64 {
+	const unixEpochJD = 2440587.5
+	return float64(t.UnixNano())/float64(24*time.Hour) + unixEpochJD
+}
+
+// LocalSiderealTime calculates the local mean sidereal time in hours, within
+// [0, 24), at the given time instant and longitude in degrees.
+func LocalSiderealTime(t time.Time, long float64) float64 {
+	// https://aa.usno.navy.mil/faq/GAST
+	const j2000 = 2451545.0
+	gmst := 18.697374558 + 24.06570982441908*(julianDate(t)-j2000)
+
+	lst := math.Mod(gmst+long/15, 24)
+	if lst < 0 {
+		lst += 24
+	}
+
+	return lst
+}
+
+// SkyBrightness estimates how bright the sky is at the given time instant and
+// location as a value within [0.0, 1.0]. Unlike the sun condition or the
+// twilight times, the brightness changes smoothly with the elevation of the
+// sun: it is 0 during astronomical night, when the sun is 18 degrees or more
+// below the horizon, 0.5 when the sun is at the horizon, and 1 when the sun is
+// 18 degrees or more above it.
+func SkyBrightness(t time.Time, lat, long float64) float64 {
+	return skyBrightness(SunElevation(t, lat, long))
+}
+
+// skyBrightness maps the sun's elevation in degrees to the sky brightness
+// using a smoothstep curve over [-18, 18].
+func skyBrightness(elevation float64) float64 {
+	x := clamp((elevation + 18) / 36)
+	return x * x * (3 - 2*x)
+}
+
+// SolarGenerationWindow calculates the time range on the given date that the
+// sun is continuously above the given elevation in degrees, such as the window
+// that solar panels are generating meaningfully. An empty TimeRange is returned
+// if the sun never gets above the elevation. If the sun never goes below the
+// elevation, then the whole day from one solar midnight to the next is
+// returned.
+func SolarGenerationWindow(date time.Time, lat, long float64, minElevationDeg float64) TimeRange {
+	day := newSunDay(date, lat, long)
+
+	ha := day.hourAngle(Radians(90 - minElevationDeg))
+	if math.IsNaN(ha) {
+		noon := day.noon()
+		if Degrees(day.elevation(0)) > minElevationDeg {
+			return TimeRange{
+				Start: noon.Add(-12 * time.Hour),
+				End:   noon.Add(+12 * time.Hour),
+			}
+		}
+		return TimeRange{}
+	}
+
+	return TimeRange{
+		Start: day.timeAt(+math.Abs(ha)),
+		End:   day.timeAt(-math.Abs(ha)),
+	}
+}
+
+// UVIndexProxy estimates the clear-sky UV index at the given time instant and
+// location from the elevation of the sun alone. It is a proxy, not a
+// measurement: clouds, ozone, altitude and ground reflection are all ignored,
+// so it should only be used as a rough upper bound. 0 is returned when the sun
+// is below the horizon.
+func UVIndexProxy(t time.Time, lat, long float64) float64 {
+	return uvIndexProxy(SunElevation(t, lat, long))
+}
+
+// uvIndexProxy calculates the clear-sky UV index from the sun's elevation in
+// degrees.
+func uvIndexProxy(elevation float64) float64 {
+	if elevation <= 0 {
+		return 0
+	}
+
+	// Madronich's approximation: UVI ≈ 12.5 * cos(zenith)^2.42.
+	return 12.5 * math.Pow(math.Sin(Radians(elevation)), 2.42)
+}
+
+// SunriseAzimuth calculates the azimuth in degrees, clockwise from north, of
+// where the sun rises on the horizon on the given date. A *ConditionError is
+// returned if the sun doesn't rise on that date.
+func SunriseAzimuth(date time.Time, lat, long float64) (float64, error) {
+	return horizonAzimuth(date, lat, long)
+}
+
+// SunsetAzimuth calculates the azimuth in degrees, clockwise from north, of
+// where the sun sets on the horizon on the given date. A *ConditionError is
+// returned if the sun doesn't set on that date.
+func SunsetAzimuth(date time.Time, lat, long float64) (float64, error) {
+	az, err := horizonAzimuth(date, lat, long)
+	if err != nil {
+		return 0, err
+	}
+	// The sun sets mirrored across the meridian from where it rises.
+	return 360 - az, nil
+}
+
+// horizonAzimuth calculates the azimuth of the sunrise.
+func horizonAzimuth(date time.Time, lat, long float64) (float64, error) {
+	day := newSunDay(date, lat, long)
+
+	const zenith = horizonZenith * math.Pi / 180
+
+	if math.IsNaN(day.hourAngle(zenith)) {
+		return 0, &ConditionError{calcCondition(day.lat, day.decl)}
+	}
+
+	// https://en.wikipedia.org/wiki/Solar_azimuth_angle
+	cosAz := (math.Sin(day.decl) - math.Sin(day.lat)*math.Cos(zenith)) /
+		(math.Cos(day.lat) * math.Sin(zenith))
+
+	return Degrees(math.Acos(math.Max(-1, math.Min(1, cosAz)))), nil
+}
+
+// SolarClockOffset calculates how far the apparent solar time is ahead of the
+// clock time in the given time instant's timezone on its date. The offset
+// includes the equation of time and the longitude correction, the same ones
+// used by CalculateSun, so it is negative if the solar noon is after 12:00 on
+// the clock.
+func SolarClockOffset(t time.Time, long float64) time.Duration {
+	// The latitude doesn't affect the time of solar noon.
+	noon := newSunDay(t, 0, long).noon()
+
+	y, m, d := noon.Date()
+	clockNoon := time.Date(y, m, d, 12, 0, 0, 0, noon.Location())
+
+	return clockNoon.Sub(noon)
+}
+
+// LocalSolarTime calculates the apparent solar time at the given time instant
+// and longitude in degrees. The solar time is returned as a time.Time in the
+// same timezone as t, so that the solar noon reads as 12:00. Note that the
+// returned time may be on a different date if t is close to midnight.
+func LocalSolarTime(t time.Time, long float64) time.Time {
+	return t.Add(SolarClockOffset(t, long))
+}
+
+// Depression angles in degrees of the sun at the start and end of each
+// twilight.
+const (
+	civilTwilightDepression        = 6
+	nauticalTwilightDepression     = 12
+	astronomicalTwilightDepression = 18
+)
+
+// twilightZenith returns the zenith angle in radians of the sun at the given
+// depression angle in degrees. Every twilight in this package, including the
+// ones of CalculateSunWithAngles and the twilight fields of Sun, measures the
+// depression from the geometric horizon at 90 degrees like NOAA does. The only
+// exception is Sun's Dawn and Dusk, which keep the original 6 degrees below
+// the horizon corrected for the atmospheric refraction.
+func twilightZenith(depressionDeg float64) float64 {
+	return Radians(90 + depressionDeg)
+}
+
+// CivilDawn calculates the time on the given date that the sun rises to 6
+// degrees below the horizon. A *ConditionError is returned if that doesn't
+// happen on the date.
+func CivilDawn(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, civilTwilightDepression, true)
+}
+
+// CivilDusk calculates the time on the given date that the sun sets to 6
+// degrees below the horizon. A *ConditionError is returned if that doesn't
+// happen on the date.
+func CivilDusk(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, civilTwilightDepression, false)
+}
+
+// NauticalDawn is like CivilDawn, except for 12 degrees below the horizon.
+func NauticalDawn(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, nauticalTwilightDepression, true)
+}
+
+// NauticalDusk is like CivilDusk, except for 12 degrees below the horizon.
+func NauticalDusk(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, nauticalTwilightDepression, false)
+}
+
+// AstronomicalDawn is like CivilDawn, except for 18 degrees below the horizon.
+func AstronomicalDawn(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, astronomicalTwilightDepression, true)
+}
+
+// AstronomicalDusk is like CivilDusk, except for 18 degrees below the horizon.
+func AstronomicalDusk(date time.Time, lat, long float64) (time.Time, error) {
+	return twilightTime(date, lat, long, astronomicalTwilightDepression, false)
+}
+
+// twilightTime calculates the time on the given date that the sun crosses the
+// given depression angle in degrees, either in the morning or the evening.
+func twilightTime(date time.Time, lat, long, depressionDeg float64, morning bool) (time.Time, error) {
+	day := newSunDay(date, lat, long)
+
+	ha := day.hourAngle(twilightZenith(depressionDeg))
+	if math.IsNaN(ha) {
+		return time.Time{}, &ConditionError{calcCondition(day.lat, day.decl)}
+	}
+
+	if morning {
+		return day.timeAt(+math.Abs(ha)), nil
+	}
+	return day.timeAt(-math.Abs(ha)), nil
+}
+
+// SundialCorrection is the correction for reading the clock time from a
+// sundial on a date.
+type SundialCorrection struct {
+	Date time.Time
+	// Correction is the duration to add to the time read from the sundial to
+	// get the clock time.
+	Correction time.Duration
+}
+
+// SundialCorrectionTable calculates the sundial corrections of the first day of
+// every month in the given year, for a sundial at the given longitude in
+// degrees with clocks in the given location. The correction combines the
+// equation of time with the distance of the longitude from the meridian of the
+// location's timezone, including DST if it's observed on the date.
+func SundialCorrectionTable(year int, long float64, loc *time.Location) []SundialCorrection {
+	table := make([]SundialCorrection, 12)
+
+	for i := range table {
+		date := time.Date(year, time.January+time.Month(i), 1, 12, 0, 0, 0, loc)
+
+		// A sundial reads the apparent solar time, which is ahead of the mean
+		// solar time by the equation of time. The mean solar time is then
+		// behind the clock by 4 minutes for every degree that the longitude is
+		// west of the zone's meridian.
+		_, offset := date.Zone()
+		meridian := time.Duration(offset)*time.Second - time.Duration(long/15*float64(time.Hour))
+
+		table[i] = SundialCorrection{
+			Date:       time.Date(year, time.January+time.Month(i), 1, 0, 0, 0, 0, loc),
+			Correction: meridian - EquationOfTime(date),
+		}
+	}
+
+	return table
+}
+
+// GoldenHour calculates the morning and evening golden hours on the date of the
+// given time instant, when the sun is between 4 degrees below and 6 degrees
+// above the horizon.
+//
+// If the sun doesn't cross both angles on the date, such as near the poles,
+// then zero times are returned. The returned condition is always that of
+// CalculateSun for the date, so it may still be NormalSun without the windows,
+// such as at 65°N in early December, when the sun rises but never reaches 6
+// degrees. Check the times with IsZero to tell if there are windows.
+func GoldenHour(t time.Time, lat, long float64) (morningStart, morningEnd, eveningStart, eveningEnd time.Time, cond SunCondition) {
+	return elevationWindows(t, lat, long, -4, 6)
+}
+
+// BlueHour is like GoldenHour, except for the blue hours, when the sun is
+// between 6 and 4 degrees below the horizon.
+func BlueHour(t time.Time, lat, long float64) (morningStart, morningEnd, eveningStart, eveningEnd time.Time, cond SunCondition) {
+	return elevationWindows(t, lat, long, -6, -4)
+}
+
+// elevationWindows calculates the start and end times of the morning and
+// evening windows that the sun is between the given elevations in degrees,
+// along with the condition of the day as described by GoldenHour.
+func elevationWindows(t time.Time, lat, long, lowElev, highElev float64) (morningStart, morningEnd, eveningStart, eveningEnd time.Time, cond SunCondition) {
+	day := newSunDay(t, lat, long)
+
+	haLow := math.Abs(day.hourAngle(Radians(90 - lowElev)))
+	haHigh := math.Abs(day.hourAngle(Radians(90 - highElev)))
+
+	cond = day.sun().Condition
+	if math.IsNaN(haLow) || math.IsNaN(haHigh) {
+		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, cond
+	}
+
+	return day.timeAt(+haLow), day.timeAt(+haHigh), day.timeAt(-haHigh), day.timeAt(-haLow), cond
+}
@@ -0,0 +1,600 @@
+package solar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunElevation(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	day := newSunDay(ts, latitude, longitude)
+
+	t.Run("noon", func(t *testing.T) {
+		expect := 90 - math.Abs(latitude-Degrees(day.decl))
+		if e := SunElevation(day.noon(), latitude, longitude); math.Abs(e-expect) > 1e-6 {
+			t.Errorf("expected noon elevation %.6f, got %.6f", expect, e)
+		}
+	})
+
+	t.Run("midnight", func(t *testing.T) {
+		expect := -90 + math.Abs(latitude+Degrees(day.decl))
+		midnight := day.noon().Add(12 * time.Hour)
+		if e := SunElevation(midnight, latitude, longitude); math.Abs(e-expect) > 1e-3 {
+			t.Errorf("expected midnight elevation %.6f, got %.6f", expect, e)
+		}
+	})
+
+	t.Run("hour angle", func(t *testing.T) {
+		for _, ha := range []float64{-math.Pi, -1, 0, 0.5, math.Pi / 2} {
+			if got := day.hourAngleAt(day.timeAt(ha)); math.Abs(got-ha) > 1e-6 {
+				t.Errorf("hour angle %f round-tripped to %f", ha, got)
+			}
+		}
+	})
+}
+
+func TestCalculateSunIterative(t *testing.T) {
+	zones := []*time.Location{time.UTC, losAngeles}
+	longitudes := []float64{-118.2, -45, 0, 45, 139.7}
+
+	for lat := -60.0; lat <= 60; lat += 15 {
+		for _, long := range longitudes {
+			for _, zone := range zones {
+				for month := time.January; month <= time.December; month++ {
+					ts := time.Date(2021, month, 15, 12, 0, 0, 0, zone)
+					name := fmt.Sprintf("%g,%g/%s/%s", lat, long, zone, month)
+
+					exact := CalculateSun(ts, lat, long)
+					iterative := calculateSunIterative(ts, lat, long)
+
+					if exact.Condition != iterative.Condition {
+						t.Errorf("%s: condition mismatch: %s != %s", name, exact.Condition, iterative.Condition)
+						continue
+					}
+
+					assertNear(t, name+" dawn", time.Minute, exact.Dawn, iterative.Dawn)
+					assertNear(t, name+" sunrise", time.Minute, exact.Sunrise, iterative.Sunrise)
+					assertNear(t, name+" sunset", time.Minute, exact.Sunset, iterative.Sunset)
+					assertNear(t, name+" dusk", time.Minute, exact.Dusk, iterative.Dusk)
+				}
+			}
+		}
+	}
+}
+
+func assertNear(t *testing.T, name string, within time.Duration, exp, got time.Time) {
+	t.Helper()
+
+	if exp.IsZero() != got.IsZero() {
+		t.Errorf("%s: expected %s, got %s", name, exp, got)
+		return
+	}
+
+	if d := got.Sub(exp); d > within || d < -within {
+		t.Errorf("%s: expected %s, got %s (off by %s)", name, exp, got, d)
+	}
+}
+
+func TestNextTimeAtElevation(t *testing.T) {
+	ts := time.Unix(1636333967-epochDay, 0).In(losAngeles)
+	ts = timeIn(t, ts, "08:00:00")
+
+	t.Run("today", func(t *testing.T) {
+		next, ok := NextTimeAtElevation(ts, latitude, longitude, 30)
+		if !ok {
+			t.Fatal("expected the sun to reach 30 degrees")
+		}
+
+		if next.Before(ts) || next.Sub(ts) > 12*time.Hour {
+			t.Errorf("expected a time later today, got %s", next)
+		}
+	})
+
+	t.Run("later season", func(t *testing.T) {
+		// The sun only gets to around 39 degrees in November in Los Angeles,
+		// so 45 degrees needs to wait until the spring.
+		next, ok := NextTimeAtElevation(ts, latitude, longitude, 45)
+		if !ok {
+			t.Fatal("expected the sun to reach 45 degrees")
+		}
+
+		from := time.Date(2022, time.February, 1, 0, 0, 0, 0, losAngeles)
+		to := time.Date(2022, time.April, 1, 0, 0, 0, 0, losAngeles)
+		if next.Before(from) || next.After(to) {
+			t.Errorf("expected a time in late winter, got %s", next)
+		}
+	})
+
+	t.Run("mismatched zone", func(t *testing.T) {
+		// With a UTC clock, the solar days at these longitudes start many hours
+		// away from midnight, so the next crossing may be on yesterday's Sun.
+		const lat, elevation = 10, 20
+
+		for _, long := range []float64{179, 150, 100, -100, -150, -179} {
+			start := time.Date(2021, time.November, 7, 0, 0, 0, 0, time.UTC)
+			for ts := start; ts.Before(start.AddDate(0, 0, 1)); ts = ts.Add(time.Hour) {
+				next, ok := NextTimeAtElevation(ts, lat, long, elevation)
+				if !ok {
+					t.Fatalf("%g at %s: expected the sun to reach %d degrees", long, ts, elevation)
+				}
+
+				if got := SunElevation(next, lat, long); math.Abs(got-elevation) > 0.5 {
+					t.Fatalf("%g at %s: expected the sun at %d degrees at %s, got %.1f", long, ts, elevation, next, got)
+				}
+
+				// The sun must stay on the same side of the elevation until
+				// shortly before the returned time.
+				above := SunElevation(ts, lat, long) > elevation
+				for at := ts; at.Before(next.Add(-10 * time.Minute)); at = at.Add(5 * time.Minute) {
+					if (SunElevation(at, lat, long) > elevation) != above {
+						t.Fatalf("%g at %s: expected the earlier crossing at %s, got %s", long, ts, at, next)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("impossible", func(t *testing.T) {
+		if next, ok := NextTimeAtElevation(ts, 70, 0, 80); ok {
+			t.Errorf("unexpected time at 80 degrees at 70N: %s", next)
+		}
+	})
+}
+
+func TestLocalSiderealTime(t *testing.T) {
+	// Examples 12.a and 12.b from Jean Meeus' Astronomical Algorithms.
+	type test struct {
+		name string
+		time time.Time
+		long float64
+		lst  float64
+	}
+
+	var tests = []test{
+		{
+			name: "Greenwich midnight",
+			time: time.Date(1987, time.April, 10, 0, 0, 0, 0, time.UTC),
+			long: 0,
+			lst:  13 + 10/60.0 + 46.3668/3600,
+		},
+		{
+			name: "Greenwich evening",
+			time: time.Date(1987, time.April, 10, 19, 21, 0, 0, time.UTC),
+			long: 0,
+			lst:  8 + 34/60.0 + 57.0896/3600,
+		},
+		{
+			name: "Washington",
+			time: time.Date(1987, time.April, 10, 19, 21, 0, 0, time.UTC),
+			long: -77.0656,
+			lst:  8 + 34/60.0 + 57.0896/3600 - 77.0656/15,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lst := LocalSiderealTime(test.time, test.long)
+			if math.Abs(lst-test.lst) > 1.0/60 {
+				t.Errorf("expected %.4fh, got %.4fh", test.lst, lst)
+			}
+		})
+	}
+}
+
+func TestSkyBrightness(t *testing.T) {
+	assert := func(elevation, min, max float64) {
+		t.Helper()
+		if b := skyBrightness(elevation); b < min || b > max {
+			t.Errorf("%.0f degrees: expected brightness within [%.2f, %.2f], got %.4f", elevation, min, max, b)
+		}
+	}
+
+	assert(-90, 0, 0)
+	assert(-18, 0, 0.001)
+	assert(-12, 0, 0.1)
+	assert(0, 0.499, 0.501)
+	assert(12, 0.9, 1)
+	assert(45, 0.999, 1)
+
+	for e := -20.0; e < 20; e++ {
+		if skyBrightness(e) > skyBrightness(e+1) {
+			t.Errorf("brightness decreased from %.0f to %.0f degrees", e, e+1)
+		}
+	}
+
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	if b := SkyBrightness(timeIn(t, ts, "00:00:00"), latitude, longitude); b != 0 {
+		t.Errorf("expected no brightness at midnight, got %f", b)
+	}
+	if b := SkyBrightness(timeIn(t, ts, "12:00:00"), latitude, longitude); b != 1 {
+		t.Errorf("expected full brightness at noon, got %f", b)
+	}
+}
+
+func TestSolarGenerationWindow(t *testing.T) {
+	t.Run("summer", func(t *testing.T) {
+		date := time.Date(2021, time.June, 21, 12, 0, 0, 0, losAngeles)
+		window := SolarGenerationWindow(date, latitude, longitude, 15)
+
+		if window.IsZero() {
+			t.Fatal("unexpected empty window")
+		}
+
+		if d := window.Duration(); d < 8*time.Hour || d > 14*time.Hour {
+			t.Errorf("expected several hours above 15 degrees, got %s", d)
+		}
+	})
+
+	t.Run("deep winter", func(t *testing.T) {
+		// Tromsø is in polar night around the winter solstice.
+		date := time.Date(2021, time.December, 21, 12, 0, 0, 0, time.UTC)
+		window := SolarGenerationWindow(date, 69.65, 18.96, 15)
+
+		if !window.IsZero() {
+			t.Errorf("expected an empty window, got %v", window)
+		}
+	})
+
+	t.Run("midnight sun", func(t *testing.T) {
+		date := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+		window := SolarGenerationWindow(date, 80, 0, 5)
+
+		if d := window.Duration(); d != 24*time.Hour {
+			t.Errorf("expected the whole day, got %s", d)
+		}
+	})
+}
+
+func TestUVIndexProxy(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+
+	if uv := UVIndexProxy(timeIn(t, ts, "00:00:00"), latitude, longitude); uv != 0 {
+		t.Errorf("expected no UV at night, got %f", uv)
+	}
+
+	if uv := uvIndexProxy(10); uv <= 0 || uv > 1 {
+		t.Errorf("expected a low UV index at 10 degrees, got %f", uv)
+	}
+
+	// The sun is almost overhead at noon at the equator on the equinox.
+	equinox := time.Date(2021, time.March, 20, 0, 0, 0, 0, time.UTC)
+	noon := newSunDay(equinox, 0, 0).noon()
+	if uv := UVIndexProxy(noon, 0, 0); uv < 11 {
+		t.Errorf("expected a high UV index near overhead sun, got %f", uv)
+	}
+}
+
+func TestSunriseAzimuth(t *testing.T) {
+	winter := time.Date(2021, time.December, 21, 12, 0, 0, 0, losAngeles)
+	summer := time.Date(2021, time.June, 21, 12, 0, 0, 0, losAngeles)
+
+	az, err := SunriseAzimuth(winter, latitude, longitude)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if az <= 90 || az >= 180 {
+		t.Errorf("expected the winter sunrise to be south of east, got %.2f", az)
+	}
+
+	az, err = SunriseAzimuth(summer, latitude, longitude)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if az <= 0 || az >= 90 {
+		t.Errorf("expected the summer sunrise to be north of east, got %.2f", az)
+	}
+
+	set, err := SunsetAzimuth(summer, latitude, longitude)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !feq(set, 360-az) {
+		t.Errorf("expected the sunset azimuth to mirror the sunrise's %.2f, got %.2f", az, set)
+	}
+
+	_, err = SunriseAzimuth(winter, 80, 0)
+	var condErr *ConditionError
+	if !errors.As(err, &condErr) || condErr.Condition != PolarNightSun {
+		t.Errorf("expected a polar night error, got %v", err)
+	}
+}
+
+func TestLocalSolarTime(t *testing.T) {
+	for _, month := range []time.Month{time.February, time.May, time.November} {
+		date := time.Date(2021, month, 3, 9, 30, 0, 0, losAngeles)
+		noon := newSunDay(date, latitude, longitude).noon()
+
+		solarNoon := LocalSolarTime(noon, longitude)
+		if h, m, s := solarNoon.Clock(); h != 12 || m != 0 || s != 0 {
+			t.Errorf("%s: expected the solar noon %v to read 12:00:00, got %v", month, noon, solarNoon)
+		}
+
+		offset := SolarClockOffset(date, longitude)
+		if solar := LocalSolarTime(date, longitude); solar.Sub(date) != offset {
+			t.Errorf("%s: expected the solar time to be offset by %v, got %v", month, offset, solar.Sub(date))
+		}
+	}
+
+	// The equation of time makes the solar noon earlier in November than in
+	// February.
+	feb := SolarClockOffset(time.Date(2021, time.February, 11, 12, 0, 0, 0, losAngeles), longitude)
+	nov := SolarClockOffset(time.Date(2021, time.November, 10, 12, 0, 0, 0, losAngeles), longitude)
+	if diff := nov - feb; diff < 25*time.Minute || diff > 35*time.Minute {
+		t.Errorf("expected the offsets to differ by about 30 minutes, got %v", diff)
+	}
+}
+
+func TestTwilightTimes(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		date := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+
+		var times []time.Time
+		for _, fn := range []func(time.Time, float64, float64) (time.Time, error){
+			AstronomicalDawn, NauticalDawn, CivilDawn,
+			CivilDusk, NauticalDusk, AstronomicalDusk,
+		} {
+			ts, err := fn(date, latitude, longitude)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			times = append(times, ts)
+		}
+
+		for i := 1; i < len(times); i++ {
+			if !times[i].After(times[i-1]) {
+				t.Errorf("twilight %d at %v is not after %v", i, times[i], times[i-1])
+			}
+		}
+	})
+
+	t.Run("consistent", func(t *testing.T) {
+		// Every twilight API must agree on the same depression angles.
+		date := time.Date(2021, time.June, 1, 12, 0, 0, 0, losAngeles)
+		sun := CalculateSun(date, latitude, longitude)
+
+		for _, test := range []struct {
+			name string
+			fn   func(time.Time, float64, float64) (time.Time, error)
+			want time.Time
+		}{
+			{"civil dawn", CivilDawn, CalculateSunWithAngles(date, latitude, longitude, 6, 6).Dawn},
+			{"civil dusk", CivilDusk, CalculateSunWithAngles(date, latitude, longitude, 6, 6).Dusk},
+			{"nautical dawn", NauticalDawn, sun.NauticalDawn},
+			{"nautical dusk", NauticalDusk, sun.NauticalDusk},
+			{"astronomical dawn", AstronomicalDawn, sun.AstronomicalDawn},
+			{"astronomical dusk", AstronomicalDusk, sun.AstronomicalDusk},
+		} {
+			ts, err := test.fn(date, latitude, longitude)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.name, err)
+			}
+			if !ts.Equal(test.want) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.want, ts)
+			}
+		}
+
+		// Sun's Dawn and Dusk are further from the horizon corrected for the
+		// atmospheric refraction, so they're a few minutes outside the civil
+		// twilight.
+		civilDawn, _ := CivilDawn(date, latitude, longitude)
+		if diff := civilDawn.Sub(sun.Dawn); diff < 3*time.Minute || diff > 7*time.Minute {
+			t.Errorf("expected Dawn to be about 5 minutes before the civil dawn, got %v", diff)
+		}
+	})
+
+	t.Run("white night", func(t *testing.T) {
+		// The sun never gets 18 degrees below the horizon in the summer at 55N,
+		// but it still gets 6 degrees below it.
+		date := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+		if _, err := CivilDawn(date, 55, 0); err != nil {
+			t.Error("unexpected civil dawn error:", err)
+		}
+
+		for name, fn := range map[string]func(time.Time, float64, float64) (time.Time, error){
+			"astronomical dawn": AstronomicalDawn,
+			"astronomical dusk": AstronomicalDusk,
+		} {
+			ts, err := fn(date, 55, 0)
+
+			var condErr *ConditionError
+			if !errors.As(err, &condErr) {
+				t.Errorf("%s: expected a ConditionError, got %v at %v", name, err, ts)
+				continue
+			}
+			if condErr.Condition != MidnightSun {
+				t.Errorf("%s: expected midnight sun, got %s", name, condErr.Condition)
+			}
+		}
+	})
+}
+
+func TestSunPosition(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	noon := newSunDay(ts, latitude, longitude).noon()
+
+	altitude, azimuth := SunPosition(noon, latitude, longitude)
+	if !feq(altitude, SunElevation(noon, latitude, longitude)) {
+		t.Errorf("expected the altitude to match SunElevation, got %f", altitude)
+	}
+	if math.Abs(azimuth-180) > 0.01 {
+		t.Errorf("expected the sun in the south at noon, got %.2f", azimuth)
+	}
+
+	for h := 1; h < 12; h++ {
+		_, morning := SunPosition(noon.Add(-time.Duration(h)*time.Hour), latitude, longitude)
+		_, evening := SunPosition(noon.Add(+time.Duration(h)*time.Hour), latitude, longitude)
+
+		if morning <= 0 || morning >= 180 {
+			t.Errorf("%dh before noon: expected the sun in the east, got %.2f", h, morning)
+		}
+		if evening <= 180 || evening >= 360 {
+			t.Errorf("%dh after noon: expected the sun in the west, got %.2f", h, evening)
+		}
+		if math.Abs(morning-(360-evening)) > 0.01 {
+			t.Errorf("%dh from noon: expected %.2f and %.2f to be symmetric", h, morning, evening)
+		}
+	}
+
+	if altitude, _ := SunPosition(noon.Add(12*time.Hour), latitude, longitude); altitude >= 0 {
+		t.Errorf("expected the sun below the horizon at midnight, got %.2f", altitude)
+	}
+
+	// The sun is in the north at noon in the southern hemisphere.
+	if _, azimuth := SunPosition(noon, -34, longitude); azimuth > 0.01 && azimuth < 359.99 {
+		t.Errorf("expected the sun in the north at noon at 34S, got %.2f", azimuth)
+	}
+}
+
+func TestSundialCorrectionTable(t *testing.T) {
+	table := SundialCorrectionTable(2021, 0, time.UTC)
+	if len(table) != 12 {
+		t.Fatalf("expected 12 months, got %d", len(table))
+	}
+
+	for i, c := range table {
+		if c.Date.Month() != time.Month(i+1) || c.Date.Day() != 1 {
+			t.Errorf("entry %d: unexpected date %v", i, c.Date)
+		}
+	}
+
+	// On the zone's meridian, the correction is the negated equation of time:
+	// the sundial is slow in the winter and fast in the autumn.
+	signs := map[time.Month]int{
+		time.February: +1,
+		time.May:      -1,
+		time.August:   +1,
+		time.November: -1,
+	}
+	for month, sign := range signs {
+		c := table[month-1].Correction
+		if (c > 0) != (sign > 0) {
+			t.Errorf("%s: expected a correction with sign %+d, got %v", month, sign, c)
+		}
+	}
+
+	if c := table[time.November-1].Correction; c > -15*time.Minute || c < -17*time.Minute {
+		t.Errorf("expected about -16 minutes in November, got %v", c)
+	}
+
+	// Los Angeles is east of the Pacific zone's meridian at 120W, so the sun
+	// is early and the sundial is fast by the difference on top of the
+	// equation of time.
+	la := SundialCorrectionTable(2021, longitude, losAngeles)
+	diff := la[time.January-1].Correction - table[time.January-1].Correction
+	if expect := time.Duration((-8 + 118.2/15) * float64(time.Hour)); absDuration(diff-expect) > time.Second {
+		t.Errorf("expected the longitude to shift the correction by %v, got %v", expect, diff)
+	}
+}
+
+func TestGoldenHour(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	morningStart, morningEnd, eveningStart, eveningEnd, cond := GoldenHour(ts, latitude, longitude)
+	if cond != NormalSun {
+		t.Fatalf("expected normal sun, got %s", cond)
+	}
+	morning := TimeRange{Start: morningStart, End: morningEnd}
+	evening := TimeRange{Start: eveningStart, End: eveningEnd}
+
+	_, blueMorningEnd, blueEveningStart, _, cond := BlueHour(ts, latitude, longitude)
+	if cond != NormalSun {
+		t.Fatalf("expected normal sun, got %s", cond)
+	}
+
+	// The blue hour leads into the golden hour in the morning, and the other
+	// way around in the evening.
+	if !blueMorningEnd.Equal(morning.Start) || !evening.End.Equal(blueEveningStart) {
+		t.Errorf("expected the blue and golden hours to be adjacent")
+	}
+
+	for name, r := range map[string]TimeRange{"morning": morning, "evening": evening} {
+		if r.Duration() <= 0 || r.Duration() > 2*time.Hour {
+			t.Errorf("%s: unexpected golden hour duration %v", name, r.Duration())
+		}
+	}
+
+	if !morning.Start.Before(sun.Sunrise) || !morning.End.After(sun.Sunrise) {
+		t.Errorf("expected the sunrise %v within the morning golden hour %v", sun.Sunrise, morning)
+	}
+	if !evening.Start.Before(sun.Sunset) || !evening.End.After(sun.Sunset) {
+		t.Errorf("expected the sunset %v within the evening golden hour %v", sun.Sunset, evening)
+	}
+
+	for _, elevation := range []float64{
+		SunElevation(morning.Start, latitude, longitude),
+		SunElevation(evening.End, latitude, longitude),
+	} {
+		if math.Abs(elevation+4) > 0.01 {
+			t.Errorf("expected the golden hour to start at -4 degrees, got %.3f", elevation)
+		}
+	}
+
+	t.Run("polar night", func(t *testing.T) {
+		morningStart, morningEnd, eveningStart, eveningEnd, cond := GoldenHour(ts, 85, 0)
+		for _, ts := range []time.Time{morningStart, morningEnd, eveningStart, eveningEnd} {
+			if !ts.IsZero() {
+				t.Errorf("expected no golden hour during polar night, got %v", ts)
+			}
+		}
+		if cond != PolarNightSun {
+			t.Errorf("expected polar night, got %s", cond)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		// The sun rises and sets at 65°N in early December, but it never gets
+		// to 6 degrees up.
+		ts := time.Date(2021, time.December, 1, 12, 0, 0, 0, time.UTC)
+		if elevation := MaxSolarElevation(ts, 65, 0); elevation >= 6 || elevation <= 0 {
+			t.Fatalf("expected the sun to peak between 0 and 6 degrees, got %.2f", elevation)
+		}
+
+		morningStart, _, _, eveningEnd, cond := GoldenHour(ts, 65, 0)
+		if expect := CalculateSun(ts, 65, 0).Condition; cond != expect || cond != NormalSun {
+			t.Errorf("expected the normal sun of the day, got %s", cond)
+		}
+		if !morningStart.IsZero() || !eveningEnd.IsZero() {
+			t.Errorf("expected no golden hour windows, got %v, %v", morningStart, eveningEnd)
+		}
+
+		// The sun does cross the blue hour band.
+		if morningStart, _, _, eveningEnd, _ := BlueHour(ts, 65, 0); morningStart.IsZero() || eveningEnd.IsZero() {
+			t.Errorf("expected blue hour windows, got %v, %v", morningStart, eveningEnd)
+		}
+	})
+}
+
+func TestSunAltitudesForDay(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+
+	points := SunAltitudesForDay(date, latitude, longitude, time.Minute)
+	if len(points) != 24*60 {
+		t.Fatalf("expected a point every minute, got %d points", len(points))
+	}
+
+	peak := points[0]
+	for _, point := range points {
+		if point.Altitude > peak.Altitude {
+			peak = point
+		}
+	}
+
+	max := MaxSolarElevation(date, latitude, longitude)
+	t.Logf("peak altitude %.3f° at azimuth %.2f°, max %.3f°", peak.Altitude, peak.Azimuth, max)
+
+	if math.Abs(peak.Altitude-max) > 0.01 {
+		t.Errorf("expected the peak altitude to be %.3f°, got %.3f°", max, peak.Altitude)
+	}
+	if math.Abs(peak.Azimuth-180) > 1 {
+		t.Errorf("expected the peak to be due south, got %.2f°", peak.Azimuth)
+	}
+
+	if points := SunAltitudesForDay(date, latitude, longitude, 0); points != nil {
+		t.Errorf("expected no points for a zero step, got %d", len(points))
+	}
+}
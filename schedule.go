@@ -0,0 +1,140 @@
+package solar
+
+import "time"
+
+// ScheduleSegment is a span of time within a Schedule during which the color
+// temperature changes linearly from From at Start to To at End. A segment with
+// From equal to To holds the temperature constant.
+type ScheduleSegment struct {
+	TimeRange
+	From Temperature
+	To   Temperature
+}
+
+// At returns the color temperature of the segment at the given time instant,
+// clamped to From before Start and to To after End.
+func (s ScheduleSegment) At(t time.Time) Temperature {
+	return interpTemp(t, s.Start, s.End, s.From, s.To, nil)
+}
+
+// Schedule is the color temperature plan for a whole day, from midnight to
+// midnight. It is the same as what CalculateTemperature gives for every time
+// instant of that day, except the Sun is only calculated once when building it,
+// so evaluating it is cheap.
+type Schedule struct {
+	// Segments are the non-empty segments of the day in order, which are a
+	// constant low, a ramp up, a constant high, a ramp down and a constant low
+	// again for a normal sun. Polar night and midnight sun have a single
+	// constant segment. If the timezone is far off the longitude, the day may
+	// instead start and end in the middle of the daylight.
+	Segments []ScheduleSegment
+	// Sun is the Sun that the schedule was built from.
+	Sun Sun
+}
+
+// BuildSchedule builds the Schedule of the day of the given date at the given
+// location, with the temperature between lo and hi. The Sun of the day before
+// or after is only calculated if its solar day overlaps with the date, which
+// happens when the timezone is far off the longitude, and for the first day of
+// a midnight sun.
+func BuildSchedule(date time.Time, lat, long float64, lo, hi Temperature) Schedule {
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+
+	sun := CalculateSun(date, lat, long)
+	yesterdaySun := func() Sun { return CalculateSun(yesterday(date), lat, long) }
+
+	schedule := Schedule{Sun: sun}
+
+	add := func(from, to time.Time, Tfrom, Tto Temperature) {
+		// A ramp that crosses midnight is cut at the clamped time, so the
+		// temperature there is interpolated along the whole ramp.
+		ramp := ScheduleSegment{TimeRange: TimeRange{Start: from, End: to}, From: Tfrom, To: Tto}
+		if from.Before(start) {
+			from = start
+			Tfrom = ramp.At(from)
+		}
+		if to.After(end) {
+			to = end
+			Tto = ramp.At(to)
+		}
+		if !from.Before(to) {
+			return
+		}
+		schedule.Segments = append(schedule.Segments, ScheduleSegment{
+			TimeRange: TimeRange{Start: from, End: to},
+			From:      Tfrom,
+			To:        Tto,
+		})
+	}
+
+	// The transitions are added in order, and the constant segments between
+	// them are filled in with the temperature of the day's Sun, the same way
+	// that calcTemp does outside of the transitions.
+	last := start
+	fill := func(to time.Time) {
+		if to.After(last) {
+			temp := calcTemp(last, sun, yesterdaySun, lo, hi, nil)
+			add(last, to, temp, temp)
+		}
+	}
+	transition := func(from, to time.Time, Tfrom, Tto Temperature) {
+		fill(from)
+		add(from, to, Tfrom, Tto)
+		if to.After(last) {
+			last = to
+		}
+	}
+	adjacent := func(sun Sun) {
+		if sun.Condition == NormalSun {
+			transition(sun.Dawn, sun.Sunrise, lo, hi)
+			transition(sun.Sunrise, sun.Sunset, hi, hi)
+			transition(sun.Sunset, sun.Dusk, hi, lo)
+		}
+	}
+
+	// Like sunAt, the evening of the previous solar day or the morning of the
+	// next one may fall on the date.
+	dayStart := timeTruncateDayLongitude(date, long)
+	if start.Before(dayStart) {
+		adjacent(CalculateSun(date.AddDate(0, 0, -1), lat, long))
+	}
+
+	switch sun.Condition {
+	case NormalSun:
+		transition(sun.Dawn, sun.Sunrise, lo, hi)
+		transition(sun.Sunset, sun.Dusk, hi, lo)
+	case MidnightSun:
+		// Like calcTemp, the first day of a midnight sun still transitions up
+		// in the morning if yesterday had a normal sun.
+		if !sun.Sunrise.IsZero() && yesterdaySun().Condition == NormalSun {
+			transition(sun.Dawn, sun.Sunrise, lo, hi)
+		}
+	}
+
+	if end.After(dayStart.Add(24 * time.Hour)) {
+		adjacent(CalculateSun(date.AddDate(0, 0, 1), lat, long))
+	}
+
+	fill(end)
+
+	return schedule
+}
+
+// At returns the color temperature of the schedule at the given time instant.
+// Time instants before or after the day of the schedule are clamped to the
+// start or end of the day.
+func (s Schedule) At(t time.Time) Temperature {
+	if len(s.Segments) == 0 {
+		return 0
+	}
+
+	for _, segment := range s.Segments {
+		if t.Before(segment.End) {
+			return segment.At(t)
+		}
+	}
+
+	return s.Segments[len(s.Segments)-1].To
+}
@@ -0,0 +1,90 @@
+package solar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	const lo, hi Temperature = 4000, 6500
+
+	tests := []struct {
+		name     string
+		lat      float64
+		long     float64
+		date     time.Time
+		segments int
+	}{
+		{"Los Angeles", latitude, longitude, time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles), 5},
+		{"Los Angeles DST", latitude, longitude, time.Date(2021, time.November, 7, 0, 0, 0, 0, losAngeles), 5},
+		{"UTC near the date line", 10, -179, time.Date(2021, time.November, 8, 0, 0, 0, 0, time.UTC), 5},
+		{"UTC far east", 10, 150, time.Date(2021, time.November, 8, 0, 0, 0, 0, time.UTC), 5},
+		{"UTC dusk across midnight", 10, -95, time.Date(2021, time.November, 8, 0, 0, 0, 0, time.UTC), 5},
+		{"Tromsø polar night", 69.65, 18.96, time.Date(2021, time.December, 21, 0, 0, 0, 0, time.UTC), 1},
+		{"Tromsø midnight sun", 69.65, 18.96, time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := BuildSchedule(test.date, test.lat, test.long, lo, hi)
+			if len(schedule.Segments) != test.segments {
+				t.Fatalf("expected %d segments, got %d: %+v", test.segments, len(schedule.Segments), schedule.Segments)
+			}
+
+			for i := 1; i < len(schedule.Segments); i++ {
+				prev, next := schedule.Segments[i-1], schedule.Segments[i]
+				if !prev.End.Equal(next.Start) || prev.To != next.From {
+					t.Errorf("segment %d does not continue from segment %d", i, i-1)
+				}
+			}
+
+			for i, segment := range schedule.Segments {
+				from, _ := CalculateTemperature(segment.Start, test.lat, test.long, lo, hi)
+				to, _ := CalculateTemperature(segment.End, test.lat, test.long, lo, hi)
+				if !feq(float64(segment.From), float64(from)) || !feq(float64(segment.To), float64(to)) {
+					t.Errorf("segment %d: expected %.0fK to %.0fK, got %.0fK to %.0fK",
+						i, from, to, segment.From, segment.To)
+				}
+			}
+
+			end := test.date.AddDate(0, 0, 1)
+			for ts := test.date; ts.Before(end); ts = ts.Add(7 * time.Minute) {
+				expect, _ := CalculateTemperature(ts, test.lat, test.long, lo, hi)
+				if temp := schedule.At(ts); !feq(float64(temp), float64(expect)) {
+					t.Fatalf("at %s: expected %.0fK, got %.0fK", ts, expect, temp)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleJSON(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+	schedule := BuildSchedule(date, latitude, longitude, 4000, 6500)
+
+	b, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatal("cannot marshal schedule:", err)
+	}
+
+	var decoded Schedule
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal("cannot unmarshal schedule:", err)
+	}
+
+	ts := schedule.Sun.Sunset.Add(10 * time.Minute)
+	if temp := decoded.At(ts); temp != schedule.At(ts) {
+		t.Errorf("expected %.0fK after decoding, got %.0fK", schedule.At(ts), temp)
+	}
+}
+
+func BenchmarkScheduleAt(b *testing.B) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+	schedule := BuildSchedule(date, latitude, longitude, 4000, 6500)
+	ts := schedule.Sun.Sunset.Add(10 * time.Minute)
+
+	for i := 0; i < b.N; i++ {
+		schedule.At(ts)
+	}
+}
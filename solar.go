@@ -5,7 +5,9 @@ package solar
 import (
 	"encoding/json"
 	"fmt"
+	"image/color"
 	"math"
+	"sort"
 	"time"
 )
 
@@ -18,8 +20,11 @@ const (
 	endTwilight   = (90.833 - 3) * math.Pi / 180
 )
 
-func degrees(rad float64) float64 { return rad * 180 / math.Pi }
-func radians(deg float64) float64 { return deg * math.Pi / 180 }
+// Degrees converts the given angle in radians to degrees.
+func Degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Radians converts the given angle in degrees to radians.
+func Radians(deg float64) float64 { return deg * math.Pi / 180 }
 
 // SunCondition describes the condition of the Sun.
 type SunCondition uint8
@@ -78,14 +83,40 @@ func (c *SunCondition) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// ConditionError is returned when a sun event doesn't happen on the requested
+// day because of the condition of the sun, such as a sunrise during a polar
+// night.
+type ConditionError struct {
+	Condition SunCondition
+}
+
+// Error implements error.
+func (err *ConditionError) Error() string {
+	return "sun event does not happen during " + err.Condition.String()
+}
+
 // Sun describes the times for various positions of the sun. The dates of the
 // timestamps will be whatever the date that was given to CalculateSun.
 type Sun struct {
+	// Dawn and Dusk are when the sun is 6 degrees below the horizon corrected
+	// for the atmospheric refraction, which is about 5 minutes further from
+	// the sunrise and sunset than the civil twilight of CivilDawn and CivilDusk.
+	// They are kept that way for compatibility, while every other twilight in
+	// this package is measured from the geometric horizon.
 	Dawn    time.Time
 	Sunrise time.Time
 	Sunset  time.Time
 	Dusk    time.Time
 
+	// NauticalDawn and NauticalDusk are when the sun is 12 degrees below the
+	// horizon, and AstronomicalDawn and AstronomicalDusk are when it's 18
+	// degrees below. They are zero if the sun doesn't reach that angle on the
+	// day, which doesn't affect the Condition.
+	NauticalDawn     time.Time
+	NauticalDusk     time.Time
+	AstronomicalDawn time.Time
+	AstronomicalDusk time.Time
+
 	// Condition determines the validity of the above times. The times are only
 	// all valid if the condition is normal (NormalSun).
 	Condition SunCondition
@@ -111,6 +142,171 @@ func (s Sun) IsSetting(now time.Time) bool {
 	return s.Condition == NormalSun && now.After(s.Sunset) && now.Before(s.Dusk)
 }
 
+// IsDaytime returns true if the sun is up at the given time instant, that is,
+// between Sunrise and Sunset. Like DayLength, if there's no sunrise or sunset,
+// true is always returned during midnight sun, and false is always returned
+// during polar night.
+func (s Sun) IsDaytime(now time.Time) bool {
+	switch {
+	case !s.Sunrise.IsZero() && !s.Sunset.IsZero():
+		return !now.Before(s.Sunrise) && now.Before(s.Sunset)
+	case s.Condition == MidnightSun:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTwilight returns true if the given time instant is between Dawn and
+// Sunrise or between Sunset and Dusk. False is always returned if the
+// condition is not normal sun.
+func (s Sun) IsTwilight(now time.Time) bool {
+	if s.Condition != NormalSun {
+		return false
+	}
+	return (!now.Before(s.Dawn) && now.Before(s.Sunrise)) ||
+		(!now.Before(s.Sunset) && now.Before(s.Dusk))
+}
+
+// SunEvent is a named time instant of the sun, such as its sunrise.
+type SunEvent struct {
+	Name string
+	Time time.Time
+}
+
+// Events returns the non-zero times of Sun as events sorted chronologically.
+// The events are named "dawn", "sunrise", "sunset" and "dusk".
+func (s Sun) Events() []SunEvent {
+	all := []SunEvent{
+		{"dawn", s.Dawn},
+		{"sunrise", s.Sunrise},
+		{"sunset", s.Sunset},
+		{"dusk", s.Dusk},
+	}
+
+	events := all[:0]
+	for _, event := range all {
+		if !event.Time.IsZero() {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events
+}
+
+// NearestEvent returns the non-zero event of Sun that is closest to the given
+// time instant, as well as the signed duration from that event to t. The delta
+// is positive if the event has already happened. An empty name is returned if
+// Sun has no events, such as during a full polar night.
+func (s Sun) NearestEvent(t time.Time) (name string, event time.Time, delta time.Duration) {
+	for i, e := range s.Events() {
+		d := t.Sub(e.Time)
+		if i == 0 || absDuration(d) < absDuration(delta) {
+			name, event, delta = e.Name, e.Time, d
+		}
+	}
+	return
+}
+
+// NextTransition returns the earliest of the non-zero Dawn, Sunrise, Sunset and
+// Dusk that is strictly after the given time instant. Unlike NextTransitionTime,
+// it only looks within this Sun. False is returned if all of them are before
+// after or there are none, such as during a full polar night.
+func (s Sun) NextTransition(after time.Time) (time.Time, bool) {
+	for _, event := range s.Events() {
+		if event.Time.After(after) {
+			return event.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// TimeRange is the range of time between Start and End.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// IsZero returns true if the range has zero Start and End times, which means it
+// is empty.
+func (r TimeRange) IsZero() bool {
+	return r.Start.IsZero() && r.End.IsZero()
+}
+
+// Duration returns the duration between Start and End.
+func (r TimeRange) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Round returns a copy of Sun with all of its non-zero times rounded to the
+// nearest multiple of d, the same way time.Time's Round does. For example,
+// rounding to time.Second gives times with no fractional seconds, which is
+// cleaner for displaying and comparing.
+func (s Sun) Round(d time.Duration) Sun {
+	round := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return t
+		}
+		return t.Round(d)
+	}
+
+	s.Dawn = round(s.Dawn)
+	s.Sunrise = round(s.Sunrise)
+	s.Sunset = round(s.Sunset)
+	s.Dusk = round(s.Dusk)
+	s.NauticalDawn = round(s.NauticalDawn)
+	s.NauticalDusk = round(s.NauticalDusk)
+	s.AstronomicalDawn = round(s.AstronomicalDawn)
+	s.AstronomicalDusk = round(s.AstronomicalDusk)
+	return s
+}
+
+// InterpolateSun linearly interpolates each event time between the Suns a and
+// b, which are usually of adjacent days, where frac 0 returns a and frac 1
+// returns b. This is useful for animating the events smoothly across days. If
+// an event only exists in one of the Suns, or if the conditions differ, then
+// the values of whichever Sun frac is closer to are used as-is.
+func InterpolateSun(a, b Sun, frac float64) Sun {
+	frac = clamp(frac)
+
+	nearest := a
+	if frac >= 0.5 {
+		nearest = b
+	}
+
+	interp := func(t1, t2, near time.Time) time.Time {
+		if t1.IsZero() || t2.IsZero() {
+			return near
+		}
+		return t1.Add(time.Duration(float64(t2.Sub(t1)) * frac))
+	}
+
+	return Sun{
+		Dawn:    interp(a.Dawn, b.Dawn, nearest.Dawn),
+		Sunrise: interp(a.Sunrise, b.Sunrise, nearest.Sunrise),
+		Sunset:  interp(a.Sunset, b.Sunset, nearest.Sunset),
+		Dusk:    interp(a.Dusk, b.Dusk, nearest.Dusk),
+
+		NauticalDawn:     interp(a.NauticalDawn, b.NauticalDawn, nearest.NauticalDawn),
+		NauticalDusk:     interp(a.NauticalDusk, b.NauticalDusk, nearest.NauticalDusk),
+		AstronomicalDawn: interp(a.AstronomicalDawn, b.AstronomicalDawn, nearest.AstronomicalDawn),
+		AstronomicalDusk: interp(a.AstronomicalDusk, b.AstronomicalDusk, nearest.AstronomicalDusk),
+
+		Condition: nearest.Condition,
+	}
+}
+
 const sclockf = "15:04:05"
 
 // ShortTime formats the time into a short string of %H:%M:%S.
@@ -126,71 +322,366 @@ const (
 	DefaultHighTemperature Temperature = 6500 // K
 )
 
+// ClampTo clamps the temperature into the given band. True is returned if the
+// temperature was outside the band and had to be clamped.
+func (t Temperature) ClampTo(lo, hi Temperature) (Temperature, bool) {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	switch {
+	case t < lo:
+		return lo, true
+	case t > hi:
+		return hi, true
+	default:
+		return t, false
+	}
+}
+
 // CurrentTemperature calls CalculateTemperature with time.Now().
 func CurrentTemperature(lat, long float64, lo, hi Temperature) (Temperature, Sun) {
 	return CalculateTemperature(time.Now(), lat, long, lo, hi)
 }
 
-// WatchCurrentTemperature watches the
-// func WatchCurrentTemperature(lat, long float64, lo, hi Temperature) <-chan Temperature {}
-
 // CalculateTemperature calculates the color temperature for the given time. The
 // given latitude must be in degrees. The given lo, hi values determine the
 // minimum and maximum temperatures.
+//
+// The returned Sun is the one that the temperature is calculated from. It is
+// usually the same as CalculateSun's, except when the timezone is far off the
+// longitude and t is in the evening of the previous solar day or the morning
+// of the next one, in which case that day's Sun is returned.
 func CalculateTemperature(t time.Time, lat, long float64, lo, hi Temperature) (Temperature, Sun) {
-	current := CalculateSun(t, lat, long)
+	current := calculateSunAt(t, lat, long)
+	yesterdaySun := func() Sun { return CalculateSun(yesterday(t), lat, long) }
+	return calcTemp(t, current, yesterdaySun, lo, hi, nil), current
+}
+
+// CalculateTemperatureFunc is like CalculateTemperature, except the transitions
+// between lo and hi are shaped by the given ease function. The ease maps the
+// position in time within a transition, from 0.0 at its start to 1.0 at its
+// end, to how far the temperature has transitioned, both within [0.0, 1.0]. A
+// nil ease is the same as EaseLinear, which is what CalculateTemperature uses.
+func CalculateTemperatureFunc(t time.Time, lat, long float64, lo, hi Temperature, ease func(float64) float64) (Temperature, Sun) {
+	current := calculateSunAt(t, lat, long)
+	yesterdaySun := func() Sun { return CalculateSun(yesterday(t), lat, long) }
+	return calcTemp(t, current, yesterdaySun, lo, hi, ease), current
+}
+
+// EaseLinear transitions at a constant rate.
+func EaseLinear(x float64) float64 {
+	return x
+}
+
+// EaseCosine eases in and out of the transition along a cosine curve, like
+// redshift does.
+func EaseCosine(x float64) float64 {
+	return (1 - math.Cos(math.Pi*x)) / 2
+}
+
+// EaseSmoothstep eases in and out of the transition along the smoothstep
+// polynomial 3x² - 2x³.
+func EaseSmoothstep(x float64) float64 {
+	return x * x * (3 - 2*x)
+}
+
+// CalculateTemperatureGated is like CalculateTemperature, except the color
+// temperature only reaches hi once the sun is above the given elevation in
+// degrees. Below that elevation, the temperature is scaled back towards lo by
+// how far the sun is above the horizon, so a sun that never clears a valley or
+// skyline keeps the screen warmer all day. A non-positive minElevation applies
+// no gate.
+func CalculateTemperatureGated(t time.Time, lat, long float64, lo, hi Temperature, minElevation float64) (Temperature, Sun) {
+	temp, sun := CalculateTemperature(t, lat, long, lo, hi)
+	if minElevation <= 0 {
+		return temp, sun
+	}
+
+	factor := clamp(SunElevation(t, lat, long) / minElevation)
+	return lo + (temp-lo)*Temperature(factor), sun
+}
+
+// CalculateTemperaturePlateau is like CalculateTemperature, except the color
+// temperature is only held at hi within the given plateau width centered on
+// solar noon. The temperature ramps from lo at dawn up to hi at the start of
+// the plateau, then back down to lo from the end of the plateau until dusk. A
+// plateau longer than the daylight behaves like CalculateTemperature. Only a
+// normal sun is affected.
+func CalculateTemperaturePlateau(t time.Time, lat, long float64, lo, hi Temperature, plateau time.Duration) (Temperature, Sun) {
+	temp, sun := CalculateTemperature(t, lat, long, lo, hi)
+	if sun.Condition != NormalSun {
+		return temp, sun
+	}
+
+	if plateau < 0 {
+		plateau = 0
+	}
+
+	noon := sun.Sunrise.Add(sun.DayLength() / 2)
+
+	plateauSun := sun
+	if start := noon.Add(-plateau / 2); start.After(sun.Sunrise) {
+		plateauSun.Sunrise = start
+	}
+	if end := noon.Add(plateau / 2); end.Before(sun.Sunset) {
+		plateauSun.Sunset = end
+	}
+
+	return calcTempNormal(t, plateauSun, lo, hi, nil), sun
+}
+
+// CalculateTemperatureAnchored is like CalculateTemperature, except the
+// transitions are held within the given clock anchors, which are durations
+// since midnight of t's date. The morning transition keeps the length of the
+// twilight but starts no earlier than morningAnchor, and the evening transition
+// ends no later than eveningAnchor. This keeps the mornings and evenings
+// feeling the same throughout the seasons, while the sun still decides when
+// the day starts and ends otherwise. An anchor of 0 applies no anchor. Only a
+// normal sun is affected.
+func CalculateTemperatureAnchored(t time.Time, lat, long float64, lo, hi Temperature, morningAnchor, eveningAnchor time.Duration) (Temperature, Sun) {
+	temp, sun := CalculateTemperature(t, lat, long, lo, hi)
+	if sun.Condition != NormalSun {
+		return temp, sun
+	}
+
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+
+	anchored := sun
+	if morningAnchor != 0 {
+		if start := midnight.Add(morningAnchor); start.After(sun.Dawn) {
+			shift := start.Sub(sun.Dawn)
+			anchored.Dawn = anchored.Dawn.Add(shift)
+			anchored.Sunrise = anchored.Sunrise.Add(shift)
+		}
+	}
+	if eveningAnchor != 0 {
+		if end := midnight.Add(eveningAnchor); end.Before(sun.Dusk) {
+			shift := sun.Dusk.Sub(end)
+			anchored.Sunset = anchored.Sunset.Add(-shift)
+			anchored.Dusk = anchored.Dusk.Add(-shift)
+		}
+	}
+
+	// Anchors that are too close together would make the transitions overlap,
+	// so meet in the middle instead.
+	if anchored.Sunset.Before(anchored.Sunrise) {
+		mid := anchored.Sunset.Add(anchored.Sunrise.Sub(anchored.Sunset) / 2)
+		anchored.Sunrise = mid
+		anchored.Sunset = mid
+	}
+
+	return calcTempNormal(t, anchored, lo, hi, nil), sun
+}
+
+// CalculateTemperatureDuration is like CalculateTemperature, except each
+// transition lasts for the given ramp duration, centered on sunrise and sunset,
+// instead of spanning the twilight. This is like gammastep's transition option,
+// and it helps near the equator, where twilight can be too short for a
+// comfortable transition. The ramp may be longer than the twilight; if it is
+// longer than the day itself, the two transitions cross before reaching hi, and
+// the temperature peaks below hi around solar noon. A ramp of 0 or less
+// switches at sunrise and sunset. Only a normal sun is affected.
+func CalculateTemperatureDuration(t time.Time, lat, long float64, lo, hi Temperature, ramp time.Duration) (Temperature, Sun) {
+	temp, sun := CalculateTemperature(t, lat, long, lo, hi)
+	if sun.Condition != NormalSun {
+		return temp, sun
+	}
+
+	if ramp <= 0 {
+		ramped := sun
+		ramped.Dawn = sun.Sunrise
+		ramped.Dusk = sun.Sunset
+		return calcTempNormal(t, ramped, lo, hi, nil), sun
+	}
+
+	// Take the lower of the two ramps, so that overlapping ramps cross instead
+	// of jumping to hi.
+	rising := interpTemp(t, sun.Sunrise.Add(-ramp/2), sun.Sunrise.Add(ramp/2), lo, hi, nil)
+	setting := interpTemp(t, sun.Sunset.Add(-ramp/2), sun.Sunset.Add(ramp/2), hi, lo, nil)
+	return Temperature(math.Min(float64(rising), float64(setting))), sun
+}
+
+// IsInTransition returns true if the color temperature is transitioning at the
+// given time instant and location, that is, if it's between dawn and sunrise
+// or between sunset and dusk. Callers can use this to update more often during
+// transitions and rarely otherwise. False is always returned if the condition
+// is not normal sun.
+func IsInTransition(t time.Time, lat, long float64) bool {
+	sun := calculateSunAt(t, lat, long)
+	return sun.IsRising(t) || sun.IsSetting(t)
+}
+
+// IsAtExtreme returns whether the color temperature at the given time instant
+// and location is pinned at lo or hi, that is, whether it's not being
+// interpolated. Both are false during a transition.
+func IsAtExtreme(t time.Time, lat, long float64, lo, hi Temperature) (atLow, atHigh bool) {
+	temp, _ := CalculateTemperature(t, lat, long, lo, hi)
+	return temp == lo, temp == hi
+}
+
+// BlueHourTemperature calculates the range of color temperatures during the
+// blue hours on the given date, when the sun is between 6 and 4 degrees below
+// the horizon in the morning or the evening. Zero temperatures are returned if
+// the sun doesn't pass through that band on the date.
+func BlueHourTemperature(date time.Time, lat, long float64, lo, hi Temperature) (min, max Temperature) {
+	return temperatureRangeBetween(date, lat, long, lo, hi, -6, -4)
+}
 
+// temperatureRangeBetween calculates the range of color temperatures while the
+// sun is between the given elevations in degrees on the given date. The
+// temperature only changes in one direction within each of the morning and
+// evening bands, so only the times at the edges are needed.
+func temperatureRangeBetween(date time.Time, lat, long float64, lo, hi Temperature, fromElev, toElev float64) (min, max Temperature) {
+	day := newSunDay(date, lat, long)
+	sun := day.sun()
+	yesterdaySun := func() Sun { return CalculateSun(yesterday(date), lat, long) }
+
+	var found bool
+
+	for _, elevation := range []float64{fromElev, toElev} {
+		ha := day.hourAngle(Radians(90 - elevation))
+		if math.IsNaN(ha) {
+			return 0, 0
+		}
+
+		for _, t := range []time.Time{day.timeAt(+math.Abs(ha)), day.timeAt(-math.Abs(ha))} {
+			temp := calcTemp(t, sun, yesterdaySun, lo, hi, nil)
+			if !found || temp < min {
+				min = temp
+			}
+			if !found || temp > max {
+				max = temp
+			}
+			found = true
+		}
+	}
+
+	return min, max
+}
+
+// calculateSunAt is like CalculateSun, except it returns the Sun of the day
+// before or after t's date instead if t is outside of the solar day of its date
+// and within the twilight or daylight of that adjacent day. This is the Sun
+// that decides the color temperature at t.
+func calculateSunAt(t time.Time, lat, long float64) Sun {
+	return sunAt(t, long, CalculateSun(t, lat, long), func(t time.Time) Sun { return CalculateSun(t, lat, long) })
+}
+
+// sunAt implements calculateSunAt for the already calculated Sun of t's date.
+// sunOf is only called to calculate the Sun of an adjacent date.
+//
+// The solar day of a date starts at the mean solar midnight of the longitude,
+// which can be up to 12 hours away from midnight in t's timezone if the two
+// disagree, such as with a UTC clock near the date line. The evening of the
+// previous solar day or the morning of the next one may then fall on t's date,
+// so those are checked as well. Only the adjacent days are calculated, and only
+// when t is outside of the solar day of its date.
+func sunAt(t time.Time, long float64, sun Sun, sunOf func(time.Time) Sun) Sun {
+	start := timeTruncateDayLongitude(t, long)
+
+	var adjacent time.Time
+	switch {
+	case t.Before(start):
+		adjacent = t.AddDate(0, 0, -1)
+	case !t.Before(start.Add(24 * time.Hour)):
+		adjacent = t.AddDate(0, 0, 1)
+	default:
+		return sun
+	}
+
+	if other := sunOf(adjacent); other.Condition == NormalSun && !t.Before(other.Dawn) && t.Before(other.Dusk) {
+		return other
+	}
+
+	return sun
+}
+
+// calcTemp calculates the color temperature for the given time using the
+// already calculated Sun of that day. yesterdaySun is only called if
+// yesterday's Sun is needed. The transitions are shaped by the given ease,
+// which is linear if nil.
+func calcTemp(t time.Time, current Sun, yesterdaySun func() Sun, lo, hi Temperature, ease func(float64) float64) Temperature {
 	switch current.Condition {
 	case NormalSun:
-		return calcTempNormal(t, current, lo, hi), current
+		return calcTempNormal(t, current, lo, hi, ease)
 	case MidnightSun:
 		// Need yesterday's sun condition to determine if we should transition
 		// from a normal sun to a midnight sun (always daytime).
-		yesterday := CalculateSun(yesterday(t), lat, long)
+		yesterday := yesterdaySun()
 		if yesterday.Condition == NormalSun && t.Before(current.Sunrise) {
-			return calcTempNormal(t, current, lo, hi), current
+			return calcTempNormal(t, current, lo, hi, ease)
 		}
 		// Yesterday was not normal sun, so probably polar night or midnight.
 		// Keep high.
-		return hi, current
+		return hi
 	case PolarNightSun:
 		// wlsunset code directly transitions this to low.
-		return lo, current
+		return lo
 	default:
 		panic("unreachable: unknown sun condition " + current.Condition.String())
 	}
 }
 
-// // NextTransitionTime calculates the next time instant that the color
-// // transitioning will begin.
-// func NextTransitionTime(t time.Time, lat, long float64) time.Time {
-// 	current := CalculateSun(t, lat, long)
-// }
+// maxTransitionSearchDays is the number of days that NextTransitionTime
+// searches forward for a transition. The longest polar night or midnight sun
+// outside the poles lasts less than this.
+const maxTransitionSearchDays = 200
+
+// NextTransitionTime calculates the next time instant strictly after t that the
+// color temperature starts changing differently, that is, the next dawn,
+// sunrise, sunset or dusk. The name of the event is also returned. Only days
+// with a normal sun are considered, so during polar night or midnight sun, the
+// search continues day by day until the sun behaves normally again. A zero time
+// is returned if no transition is found within 200 days.
+func NextTransitionTime(t time.Time, lat, long float64) (time.Time, string) {
+	// Start from yesterday, since its evening can still be ahead of t if the
+	// solar day starts late in t's timezone; see sunAt.
+	for i := -1; i <= maxTransitionSearchDays; i++ {
+		sun := CalculateSun(t.AddDate(0, 0, i), lat, long)
+		if sun.Condition != NormalSun {
+			continue
+		}
+
+		for _, event := range sun.Events() {
+			if event.Time.After(t) {
+				return event.Time, event.Name
+			}
+		}
+	}
 
-func calcTempNormal(t time.Time, sun Sun, lo, hi Temperature) Temperature {
+	return time.Time{}, ""
+}
+
+func calcTempNormal(t time.Time, sun Sun, lo, hi Temperature, ease func(float64) float64) Temperature {
 	switch {
 	case t.Before(sun.Dawn):
 		return lo
 	case t.Before(sun.Sunrise):
-		return interpTemp(t, sun.Dawn, sun.Sunrise, lo, hi)
+		return interpTemp(t, sun.Dawn, sun.Sunrise, lo, hi, ease)
 	case t.Before(sun.Sunset):
 		return hi
 	case t.Before(sun.Dusk):
-		return interpTemp(t, sun.Sunset, sun.Dusk, hi, lo)
+		return interpTemp(t, sun.Sunset, sun.Dusk, hi, lo, ease)
 	default:
 		return lo
 	}
 }
 
 // interpTemp interpolates the temperature to the given time instant and time
-// range.
-func interpTemp(t, start, stop time.Time, Tstart, Tstop Temperature) Temperature {
+// range. The position in the time range is shaped by the given ease, which is
+// linear if nil.
+func interpTemp(t, start, stop time.Time, Tstart, Tstop Temperature, ease func(float64) float64) Temperature {
 	if Tstart == Tstop {
 		return Tstop
 	}
 
 	timePos := float64(t.Sub(start)) / float64(stop.Sub(start))
 	timePos = clamp(timePos)
+	if ease != nil {
+		timePos = clamp(ease(timePos))
+	}
 
 	tempPos := float64(Tstop-Tstart) * timePos
 	return Tstart + Temperature(tempPos)
@@ -202,16 +693,28 @@ func yesterday(t time.Time) time.Time {
 }
 
 func daysInYear(t time.Time) int {
-	// Get the January 1st time for the next year, then subtract 1ns. That'll
-	// take us back to right before New Year, which is the final day of the
-	// year.
-	jan1 := time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, t.Location())
-	jan1 = jan1.Add(-1)
-	return jan1.YearDay()
+	if isLeap(t.Year()) {
+		return 366
+	}
+	return 365
 }
 
-func dateOrbitAngle(t time.Time) float64 {
-	return (2.0 * math.Pi / float64(daysInYear(t))) * float64(t.YearDay())
+// isLeap returns true if the given year is a leap year in the Gregorian
+// calendar.
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// dateOrbitAngle calculates the orbit angle (fractional year) of the given
+// date in a year of the given length in days.
+func dateOrbitAngle(t time.Time, daysPerYear float64) float64 {
+	return yearDayOrbitAngle(t.YearDay(), daysPerYear)
+}
+
+// yearDayOrbitAngle is like dateOrbitAngle, except it takes the day of the year
+// starting from 1 instead of a date.
+func yearDayOrbitAngle(yearDay int, daysPerYear float64) float64 {
+	return (2.0 * math.Pi / daysPerYear) * float64(yearDay)
 }
 
 // equationOfTime calculates the equation of time (eqtime) from the given orbit
@@ -225,6 +728,21 @@ func equationOfTime(orbitAngle float64) float64 {
 		0.040849*math.Sin(2*orbitAngle))
 }
 
+// eqtimeDuration converts the equation of time returned by equationOfTime into
+// a time.Duration.
+func eqtimeDuration(eqtime float64) time.Duration {
+	// The equation of time is in minute radians.
+	return time.Duration(Degrees(eqtime) * float64(time.Minute))
+}
+
+// EquationOfTime calculates the equation of time on the date of the given time
+// instant, which is how far the apparent solar time, as read on a sundial, is
+// ahead of the mean solar time. It is at its highest of about +16 minutes in
+// early November and its lowest of about -14 minutes in mid-February.
+func EquationOfTime(t time.Time) time.Duration {
+	return eqtimeDuration(equationOfTime(dateOrbitAngle(t, float64(daysInYear(t)))))
+}
+
 func sunDeclination(orbitAngle float64) float64 {
 	// https://www.esrl.noaa.gov/gmd/grad/solcalc/solareqns.PDF
 	return 0.006918 -
@@ -239,7 +757,7 @@ func sunDeclination(orbitAngle float64) float64 {
 func sunHourAngle(latitude, declination, targetSun float64) float64 {
 	// https://www.esrl.noaa.gov/gmd/grad/solcalc/solareqns.PDF
 	return math.Acos(math.Cos(targetSun)/
-		math.Cos(latitude)*math.Cos(declination) -
+		(math.Cos(latitude)*math.Cos(declination)) -
 		math.Tan(latitude)*math.Tan(declination))
 }
 
@@ -248,14 +766,20 @@ func sunHourAngle(latitude, declination, targetSun float64) float64 {
 func hourAngleToSecondsOffset(hourAngle, eqtime float64) float64 {
 	// https://www.esrl.noaa.gov/gmd/grad/solcalc/solareqns.PDF
 	// The results of the inner math is in minute radians, so we convert it to
-	// minute degrees before multiplying by 60 (seconds a minute).
-	return degrees((4.0*math.Pi - 4*hourAngle - eqtime) * 60)
+	// minute degrees before multiplying by 60 (seconds a minute). NOAA's
+	// formula is 720 - 4*ha - eqtime in minutes with ha in degrees: 4π in
+	// degrees is 720, the minutes from midnight to noon, and 4*ha is the Earth
+	// turning 4 minutes per degree. The offset is relative to the mean solar
+	// midnight from timeTruncateDayLongitude, so no longitude term is needed.
+	return Degrees((4.0*math.Pi - 4*hourAngle - eqtime) * 60)
 }
 
-// longitudeTimeOffset calculates the longitude offset in seconds.
+// longitudeTimeOffset calculates how far the mean solar time at the given
+// longitude in degrees is ahead of UTC in seconds.
 func longitudeTimeOffset(long float64) float64 {
-	const halfDay = 43200
-	return long * halfDay / math.Pi
+	// The Earth turns 360 degrees in a day, which is 4 minutes per degree.
+	const secondsPerDegree = 24 * 60 * 60 / 360
+	return long * secondsPerDegree
 }
 
 // LocalLongitude estimates the longitude using the system timezone.
@@ -264,10 +788,29 @@ func LocalLongitude() float64 {
 }
 
 // TimeLongitude estimates the longitude from the given time instant. It uses
-// the timezone to estimate.
+// the timezone to estimate. The estimate is approximate, since it assumes that
+// DST always shifts the clock by an hour; see TimeLongitudeZone.
 func TimeLongitude(t time.Time) float64 {
+	return TimeLongitudeDST(t, true)
+}
+
+// TimeLongitudeZone is like TimeLongitude, except it uses the standard time
+// offset of the timezone instead of guessing how much DST shifts the clock. The
+// standard offset is the smaller of the offsets in January and July, so this is
+// also correct for zones whose DST shift isn't an hour, such as Lord Howe
+// Island's 30 minutes.
+func TimeLongitudeZone(t time.Time) float64 {
+	return float64(standardOffset(t)) / 60 / 60 * 15
+}
+
+// TimeLongitudeDST is like TimeLongitude, except the caller can choose whether
+// or not to undo Daylight Saving Time. If adjustDST is true, then an hour is
+// subtracted from the timezone offset during DST, which is what TimeLongitude
+// does. This is wrong for zones whose DST shift isn't exactly an hour, or if
+// the given time instant is already in standard time.
+func TimeLongitudeDST(t time.Time, adjustDST bool) float64 {
 	_, offset := t.Zone()
-	if t.IsDST() {
+	if adjustDST && t.IsDST() {
 		// DST sets the clock forward 1 hour, so we shift it back.
 		offset -= 1 * 60 * 60
 	}
@@ -279,24 +822,39 @@ func TimeLongitude(t time.Time) float64 {
 	return float64(offset) / 60 / 60 * 15
 }
 
-// timeTruncateDayLongitude calls timeTruncateDay on the given time instant,
-// then adds the longitude time offset.
+// LongitudeMismatchThreshold is how far the mean solar time at a longitude may
+// be from a timezone's standard time before CheckLongitudeConsistency considers
+// them inconsistent. Some zones, such as China's, legitimately span more than
+// an hour, so this is generous.
+const LongitudeMismatchThreshold = 2 * time.Hour
+
+// CheckLongitudeConsistency checks whether the given longitude in degrees is
+// plausible for the timezone of the given time instant. offBy is how far the
+// zone's standard time, ignoring DST, is ahead of the mean solar time at the
+// longitude. ok is false if offBy is beyond LongitudeMismatchThreshold, which
+// usually means that the longitude or the timezone is wrong, such as a
+// longitude of 0 with a UTC-8 zone.
+func CheckLongitudeConsistency(t time.Time, long float64) (offBy time.Duration, ok bool) {
+	zone := time.Duration(standardOffset(t)) * time.Second
+	meanSolar := time.Duration(long / 15 * float64(time.Hour))
+
+	offBy = zone - meanSolar
+	return offBy, absDuration(offBy) <= LongitudeMismatchThreshold
+}
+
+// timeTruncateDayLongitude returns the start of the day of the given time
+// instant in the local mean solar time of the given longitude, that is, the
+// midnight at which the hour angle calculations start.
+//
+// The mean solar time at the longitude is 4 minutes ahead of UTC per degree
+// east, and the start of day from timeTruncateDay is in the zone's standard
+// time, so the difference between the two is added to it. The difference is
+// wrapped to within 12 hours, so that when the timezone disagrees with the
+// longitude by more than half a day, such as near the date line, the solar day
+// that mostly overlaps with the local date is used.
 func timeTruncateDayLongitude(t time.Time, long float64) time.Time {
-	offset := longitudeTimeOffset(long)
-	// Round the offset to an hour. The offset is, for some reason, longer than
-	// an hour. For example, given the longitude of Los Angeles, we get a ~3.5
-	// hours offset shifting back, which is unusual. Doing it this way gives us
-	// a much more accurate result, however.
-	//
-	// For example, before giving the longitude, the calculated sunrise time was
-	// 6:40AM in the tests, while Google reported 6:19AM. With the right
-	// longitude, the calculated time is 6:10AM, which is much closer.
-	//
-	// The unexpected offset could be attributed to both the halfDay constant in
-	// wlsunset and the fact that time.Time already includes timezone
-	// information, while time_t operates on Unix epoch (which doesn't have a
-	// timezone).
-	offset = math.Mod(offset, 1*60*60)
+	offset := float64(standardOffset(t)) - longitudeTimeOffset(long)
+	offset = math.Remainder(offset, 24*60*60)
 
 	t = timeTruncateDay(t)
 	t = timeAddSeconds(t, offset)
@@ -305,31 +863,43 @@ func timeTruncateDayLongitude(t time.Time, long float64) time.Time {
 }
 
 // timeTruncateDay truncates the given time to the start of day using the
-// current time instant's timezone.
+// current time instant's timezone, ignoring DST.
+//
+// The sun calculations add seconds to the start of day and assume that every
+// day is exactly 24 hours long, so the start of day is always taken in the
+// zone's standard time: on DST days, it is an hour after midnight on the clock.
+// This keeps the calculated events continuous across DST changes, and the
+// results only depend on the date of t, not the time of the day.
 func timeTruncateDay(t time.Time) time.Time {
-	// Calculate the offset from t to get the time the day started relative to
-	// this current timezone independently of DST.
-	toffset := 0 +
-		time.Duration(t.Hour())*time.Hour +
-		time.Duration(t.Minute())*time.Minute +
-		time.Duration(t.Second())*time.Second +
-		time.Duration(t.Nanosecond())
-
-	t = t.Add(-toffset)
-	return t
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	midnight = midnight.Add(-time.Duration(standardOffset(t)) * time.Second)
+	return midnight.In(t.Location())
 }
 
-// timeAddSeconds adds the given seconds in float64 to the given time instant.
-// If secs is NaN, then t is returned.
+// standardOffset returns the offset in seconds east of UTC of the standard
+// time, that is, without DST, in the given time instant's timezone during its
+// year. It assumes that DST always moves the clock forward.
+func standardOffset(t time.Time) int {
+	_, jan := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location()).Zone()
+	_, jul := time.Date(t.Year(), time.July, 1, 0, 0, 0, 0, t.Location()).Zone()
+	if jan < jul {
+		return jan
+	}
+	return jul
+}
+
+// timeAddSeconds adds the given seconds in float64 to the given time instant,
+// rounded to the nearest nanosecond. If secs is NaN, then a zero time is
+// returned.
 func timeAddSeconds(t time.Time, secs float64) time.Time {
 	if math.IsNaN(secs) {
 		return time.Time{}
 	}
 
-	s, ns := math.Modf(secs)
-	t = t.Add(time.Duration(s) * time.Second)
-	t = t.Add(time.Duration(ns * float64(time.Second)))
-	return t
+	// Rounding the whole duration at once avoids the truncation error of
+	// converting the fractional part separately.
+	return t.Add(time.Duration(math.Round(secs * float64(time.Second))))
 }
 
 func calcCondition(latitudeRad, sunDeclination float64) SunCondition {
@@ -345,38 +915,237 @@ func calcCondition(latitudeRad, sunDeclination float64) SunCondition {
 // its condition at the given time and latitude. The given latitude and
 // longitude must be in degrees.
 //
-// The given longitude is only used to improve the accuracy of the result. The
-// values of the given longitude will vary the results by +-1 hour.
+// The given longitude shifts the times by 4 minutes per degree away from the
+// meridian of t's timezone, so a longitude that is off by 15 degrees makes the
+// times an hour off.
 //
 // If the returned Sun data has a non-normal condition, that is, if it's
 // midnight sun or polar night sun, then some of the time values may be zero.
 func CalculateSun(t time.Time, lat, long float64) Sun {
-	t = timeTruncateDayLongitude(t, long)
-	latitudeRad := radians(lat)
+	return newSunDay(t, lat, long).sun()
+}
+
+// CalculateSunUTC is like CalculateSun, except it skips the timezone and
+// longitude adjustments entirely. The date of t is taken as is, and the
+// returned times are in the local mean solar time of the observer but
+// represented in UTC, which is the same as the sun at longitude 0 in UTC. For
+// example, the solar noon is at 12:00 minus the equation of time. This is
+// useful for validating the math against reference tables and for callers
+// that work in solar time directly.
+func CalculateSunUTC(t time.Time, lat float64) Sun {
+	y, m, d := t.Date()
+	orbitAngle := dateOrbitAngle(t, float64(daysInYear(t)))
+
+	day := sunDay{
+		start:  time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+		lat:    Radians(lat),
+		decl:   sunDeclination(orbitAngle),
+		eqtime: equationOfTime(orbitAngle),
+	}
+	return day.sun()
+}
+
+// CalculateSunCustomYear is like CalculateSun, except the orbit angle of the
+// date is calculated as if a year had the given number of days instead of
+// Earth's 365 or 366. The day number still counts from January 1st of t's
+// year. This is mostly useful for fictional planets; the declination and
+// equation of time curves are still Earth's, only stretched to the new year
+// length.
+func CalculateSunCustomYear(t time.Time, lat, long float64, daysPerYear float64) Sun {
+	return newSunDayYear(t, lat, long, daysPerYear).sun()
+}
+
+// CalculateSunWithAngles is like CalculateSun, except Dawn and Dusk are when
+// the sun is at the given depression angles in degrees below the geometric
+// horizon. For example, 6 gives the same times as CivilDawn and CivilDusk, 12
+// the nautical ones and 18 the astronomical ones. Note that 6 is slightly later
+// than CalculateSun's Dawn, which is 6 degrees below the horizon corrected for
+// the atmospheric refraction. Each angle is checked on its own, so Dawn may be
+// zero while Dusk isn't, in which case the condition is not normal.
+func CalculateSunWithAngles(t time.Time, lat, long float64, dawnAngle, duskAngle float64) Sun {
+	dawnZenith := twilightZenith(dawnAngle)
+	duskZenith := twilightZenith(duskAngle)
+	return newSunDay(t, lat, long).sunWithZeniths(dawnZenith, endTwilight, duskZenith)
+}
+
+// SunFromOrbitAngle calculates the Sun like CalculateSun, except the orbit
+// angle (fractional year) in radians is given directly instead of being
+// calculated from the date. This is useful for callers with their own date
+// model, such as historical calendars. The orbit angle is 0 at the start of
+// the year and 2π at the end. The times are on the day of dayStart, which is
+// truncated for the longitude the same way CalculateSun truncates its time.
+func SunFromOrbitAngle(orbitAngle, lat, long float64, dayStart time.Time) Sun {
+	day := sunDay{
+		start:  timeTruncateDayLongitude(dayStart, long),
+		lat:    Radians(lat),
+		decl:   sunDeclination(orbitAngle),
+		eqtime: equationOfTime(orbitAngle),
+	}
+	return day.sun()
+}
 
-	orbitAngle := dateOrbitAngle(t)
-	decl := sunDeclination(orbitAngle)
-	eqtime := equationOfTime(orbitAngle)
+// CalculateSunFast calculates the Sun for each of the given latitudes on the
+// same meridian at once, which is much faster than calling CalculateSun for
+// each of them when rendering many points. The returned slice is in the same
+// order as lats.
+//
+// The date-dependent terms are only calculated once, and only the Dawn,
+// Sunrise, Sunset, Dusk and Condition fields are filled. The nautical and
+// astronomical twilight times are left zero. The filled times differ from
+// CalculateSun's by no more than floating point rounding, which is well within
+// a second.
+func CalculateSunFast(t time.Time, lats []float64, long float64) []Sun {
+	day := newSunDay(t, 0, long)
+
+	cosDecl := math.Cos(day.decl)
+	tanDecl := math.Tan(day.decl)
+	cosTwilight := math.Cos(startTwilight) / cosDecl
+	cosDaylight := math.Cos(endTwilight) / cosDecl
+
+	suns := make([]Sun, len(lats))
+	for i, lat := range lats {
+		lat = Radians(lat)
+		cosLat := math.Cos(lat)
+		tanLatDecl := math.Tan(lat) * tanDecl
+
+		haTwilight := math.Acos(cosTwilight/cosLat - tanLatDecl)
+		haDaylight := math.Acos(cosDaylight/cosLat - tanLatDecl)
+
+		sun := Sun{
+			Dawn:    day.timeAt(+haTwilight),
+			Sunrise: day.timeAt(+haDaylight),
+			Sunset:  day.timeAt(-haDaylight),
+			Dusk:    day.timeAt(-haTwilight),
+		}
 
-	haTwilight := sunHourAngle(latitudeRad, decl, startTwilight)
-	haDaylight := sunHourAngle(latitudeRad, decl, endTwilight)
+		if math.IsNaN(haTwilight) || math.IsNaN(haDaylight) {
+			sun.Condition = calcCondition(lat, day.decl)
+		}
 
+		suns[i] = sun
+	}
+
+	return suns
+}
+
+// CalculateSunGeometric is like CalculateSun, except the angles are relative to
+// the geometric horizon at 90 degrees instead of the horizon corrected for the
+// atmospheric refraction at 90.833 degrees, so the sun rises slightly later and
+// sets slightly earlier.
+func CalculateSunGeometric(t time.Time, lat, long float64) Sun {
+	const offset = (horizonZenith - 90) * math.Pi / 180
+	return newSunDay(t, lat, long).sunWithZeniths(
+		startTwilight-offset,
+		endTwilight-offset,
+		startTwilight-offset,
+	)
+}
+
+// CalculateSunAtElevation is like CalculateSun, except the sunrise and sunset
+// are for an observer at the given elevation above sea level in meters. The
+// horizon of an elevated observer dips below the astronomical horizon by about
+// 2.076*sqrt(elevation) arcminutes, so the sun rises earlier and sets later. A
+// non-positive elevation is the same as sea level.
+func CalculateSunAtElevation(t time.Time, lat, long, elevationMeters float64) Sun {
+	var dip float64
+	if elevationMeters > 0 {
+		dip = Radians(2.076 * math.Sqrt(elevationMeters) / 60)
+	}
+
+	return newSunDay(t, lat, long).sunWithZeniths(startTwilight, endTwilight+dip, startTwilight)
+}
+
+// horizonZenith is the zenith angle in degrees of the horizon, corrected for
+// the atmospheric refraction.
+const horizonZenith = 90.833
+
+// sun calculates the Sun for the day.
+func (d sunDay) sun() Sun {
+	return d.sunWithZeniths(startTwilight, endTwilight, startTwilight)
+}
+
+// sunWithZeniths calculates the Sun for the day with Dawn, Sunrise and Sunset,
+// and Dusk at the given zenith angles in radians.
+func (d sunDay) sunWithZeniths(dawnZenith, daylightZenith, duskZenith float64) Sun {
+	haDawn := d.hourAngle(dawnZenith)
+	haDusk := d.hourAngle(duskZenith)
+	haDaylight := d.hourAngle(daylightZenith)
+
+	// The morning and evening times use the same hour angle on either side of
+	// the same solar noon, so Sunset is never before Sunrise, even near the
+	// date line.
 	sun := Sun{
-		Dawn:    timeAddSeconds(t, hourAngleToSecondsOffset(+math.Abs(haTwilight), eqtime)),
-		Dusk:    timeAddSeconds(t, hourAngleToSecondsOffset(-math.Abs(haTwilight), eqtime)),
-		Sunrise: timeAddSeconds(t, hourAngleToSecondsOffset(+math.Abs(haDaylight), eqtime)),
-		Sunset:  timeAddSeconds(t, hourAngleToSecondsOffset(-math.Abs(haDaylight), eqtime)),
+		Dawn:    d.timeAt(+math.Abs(haDawn)),
+		Dusk:    d.timeAt(-math.Abs(haDusk)),
+		Sunrise: d.timeAt(+math.Abs(haDaylight)),
+		Sunset:  d.timeAt(-math.Abs(haDaylight)),
 	}
 
-	if math.IsNaN(haTwilight) || math.IsNaN(haDaylight) {
-		sun.Condition = calcCondition(latitudeRad, decl)
+	if math.IsNaN(haDawn) || math.IsNaN(haDusk) || math.IsNaN(haDaylight) {
+		sun.Condition = calcCondition(d.lat, d.decl)
 	} else {
 		sun.Condition = NormalSun
 	}
 
+	haNautical := d.hourAngle(twilightZenith(nauticalTwilightDepression))
+	sun.NauticalDawn = d.timeAt(+math.Abs(haNautical))
+	sun.NauticalDusk = d.timeAt(-math.Abs(haNautical))
+
+	haAstronomical := d.hourAngle(twilightZenith(astronomicalTwilightDepression))
+	sun.AstronomicalDawn = d.timeAt(+math.Abs(haAstronomical))
+	sun.AstronomicalDusk = d.timeAt(-math.Abs(haAstronomical))
+
 	return sun
 }
 
+// sunDay contains the date-dependent values needed to calculate the position
+// of the sun throughout a single day.
+type sunDay struct {
+	start  time.Time // start of day, adjusted for the longitude
+	lat    float64   // latitude in radians
+	decl   float64   // sun declination in radians
+	eqtime float64   // equation of time
+}
+
+// newSunDay calculates the sunDay for the day of the given time instant. The
+// given latitude and longitude must be in degrees.
+func newSunDay(t time.Time, lat, long float64) sunDay {
+	return newSunDayYear(t, lat, long, 0)
+}
+
+// newSunDayYear is like newSunDay, except the year has the given number of
+// days. If daysPerYear is 0, then the number of days in t's year is used.
+func newSunDayYear(t time.Time, lat, long float64, daysPerYear float64) sunDay {
+	if daysPerYear == 0 {
+		daysPerYear = float64(daysInYear(t))
+	}
+
+	// Use the date of t itself for the orbit angle. The start of the day may
+	// fall on the previous date once shifted for the longitude or DST, which
+	// would otherwise make two consecutive dates share the same orbit angle.
+	orbitAngle := dateOrbitAngle(t, daysPerYear)
+
+	return sunDay{
+		start:  timeTruncateDayLongitude(t, long),
+		lat:    Radians(lat),
+		decl:   sunDeclination(orbitAngle),
+		eqtime: equationOfTime(orbitAngle),
+	}
+}
+
+// hourAngle calculates the hour angle at which the sun reaches the given zenith
+// angle in radians. NaN is returned if the sun never reaches that angle.
+func (d sunDay) hourAngle(zenith float64) float64 {
+	return sunHourAngle(d.lat, d.decl, zenith)
+}
+
+// timeAt returns the time instant of the given hour angle. A positive hour
+// angle is before solar noon, and a negative one is after. If hourAngle is NaN,
+// then a zero time is returned.
+func (d sunDay) timeAt(hourAngle float64) time.Time {
+	return timeAddSeconds(d.start, hourAngleToSecondsOffset(hourAngle, d.eqtime))
+}
+
 func throwf(f string, v ...interface{}) {
 	panic(fmt.Sprintf(f, v...))
 }
@@ -451,36 +1220,55 @@ func planckianLocus(temp float64) (x, y float64) {
 	return
 }
 
+// srgbGamma applies the sRGB transfer function with the given gamma. The
+// returned value is always within [0.0, 1.0]; NaN or non-positive values are
+// treated as 0, and a gamma of 1.0 or an invalid gamma (NaN or non-positive)
+// leaves the value linear.
 func srgbGamma(value, gamma float64) float64 {
 	// https://en.wikipedia.org/wiki/SRGB
-	if value <= 0.0031308 {
+	switch {
+	case math.IsNaN(value) || value <= 0:
+		return 0
+	case gamma == 1 || math.IsNaN(gamma) || gamma <= 0:
+		return clamp(value)
+	case value <= 0.0031308:
 		return 12.92 * value
-	} else {
-		return math.Pow(1.055*value, 1.0/gamma) - 0.055
+	default:
+		return clamp(math.Pow(1.055*value, 1.0/gamma) - 0.055)
 	}
 }
 
-// clamp clamps the given value between [0.0, 1.0].
+// clamp clamps the given value between [0.0, 1.0]. NaN is clamped to 0.0.
 func clamp(value float64) float64 {
 	switch {
 	case value > 1.0:
 		return 1.0
-	case value < 0.0:
+	case value < 0.0, math.IsNaN(value):
 		return 0.0
 	}
 	return value
 }
 
-func xyzToSRGB(x, y, z float64) (r, g, b float64) {
+// xyzToSRGB converts the given XYZ color to sRGB corrected with the given
+// gamma. Channels outside the sRGB gamut are clamped, so the returned values
+// are always within [0.0, 1.0], even for NaN or infinite inputs.
+func xyzToSRGB(x, y, z, gamma float64) (r, g, b float64) {
 	// http://www.brucelindbloom.com/index.html?Eqn_RGB_XYZ_Matrix.html
-	r = srgbGamma(clamp((3.2404542*x)-(1.5371385*y)-(0.4985314*z)), 2.2)
-	g = srgbGamma(clamp((-0.9692660*x)+(1.8760108*y)+(0.0415560*z)), 2.2)
-	b = srgbGamma(clamp((0.0556434*x)-(0.2040259*y)+(1.0572252*z)), 2.2)
+	r = srgbGamma(clamp((3.2404542*x)-(1.5371385*y)-(0.4985314*z)), gamma)
+	g = srgbGamma(clamp((-0.9692660*x)+(1.8760108*y)+(0.0415560*z)), gamma)
+	b = srgbGamma(clamp((0.0556434*x)-(0.2040259*y)+(1.0572252*z)), gamma)
 	return
 }
 
+// srgbNormalize scales the given channels so that the largest one is 1.0. If
+// no channel is positive or the largest one is infinite, then (0, 0, 0) is
+// returned instead of NaNs.
 func srgbNormalize(r, g, b float64) (r1, g1, b1 float64) {
 	maxw := math.Max(r, math.Max(g, b))
+	if !(maxw > 0) || math.IsInf(maxw, 0) {
+		return 0, 0, 0
+	}
+
 	r /= maxw
 	g /= maxw
 	b /= maxw
@@ -497,6 +1285,19 @@ func srgbNormalize(r, g, b float64) (r1, g1, b1 float64) {
 // The returned red, green and blue values are within [0.0, 1.0] in interval
 // notation. A temperature value of 6500K will return (1.0, 1.0, 1.0) for white.
 func CalculateWhitepoint(temp Temperature) (rw, gw, bw float64) {
+	return CalculateWhitepointGamma(temp, DefaultGamma)
+}
+
+// DefaultGamma is the gamma that CalculateWhitepoint uses, which is the same as
+// wlsunset's.
+const DefaultGamma = 2.2
+
+// CalculateWhitepointGamma is like CalculateWhitepoint, except the channels are
+// corrected with the given gamma instead of DefaultGamma. A gamma of 1.0 gives
+// linear-light RGB for further processing, 2.2 is the same as
+// CalculateWhitepoint, and 2.4 is the exponent of the piecewise sRGB transfer
+// function. An invalid gamma (NaN or non-positive) is treated as 1.0.
+func CalculateWhitepointGamma(temp Temperature, gamma float64) (rw, gw, bw float64) {
 	if temp == 6500 {
 		rw = 1
 		gw = 1
@@ -504,29 +1305,41 @@ func CalculateWhitepoint(temp Temperature) (rw, gw, bw float64) {
 		return
 	}
 
-	x, y := 1.0, 1.0
+	x, y := whitepointChromaticity(temp)
+	return chromaticityToRGBGamma(x, y, gamma)
+}
 
-	switch {
-	case temp >= 25000:
-		x, y = illuminantD(25000)
-	case temp >= 4000:
-		x, y = illuminantD(float64(temp))
-	case temp >= 2500:
-		x1, y1 := illuminantD(float64(temp))
-		x2, y2 := planckianLocus(float64(temp))
-		factor := float64((4000 - temp) / 1500)
-		sinefactor := (math.Cos(math.Pi*factor) + 1.0) / 2.0
-		x = x1*sinefactor + x2*(1.0-sinefactor)
-		y = y1*sinefactor + y2*(1.0-sinefactor)
-	case temp >= 1667:
-		x, y = planckianLocus(float64(temp))
-	default:
-		x, y = planckianLocus(1667)
-	}
+// RGB returns the whitepoint of the temperature. It is the same as calling
+// CalculateWhitepoint.
+func (t Temperature) RGB() (r, g, b float64) {
+	return CalculateWhitepoint(t)
+}
+
+// Color returns the whitepoint of the temperature as an opaque color.NRGBA.
+func (t Temperature) Color() color.Color {
+	r, g, b := CalculateWhitepoint8(t)
+	return color.NRGBA{R: r, G: g, B: b, A: 255}
+}
+
+// whitepointChromaticity calculates the xy chromaticity of the whitepoint for
+// the given color temperature. The temperature is clamped the same way
+// CalculateWhitepoint does.
+func whitepointChromaticity(temp Temperature) (x, y float64) {
+	return DefaultWhitepointBlend.chromaticity(temp)
+}
+
+// chromaticityToRGB converts the given xy chromaticity to normalized sRGB
+// channels using the default gamma.
+func chromaticityToRGB(x, y float64) (r, g, b float64) {
+	return chromaticityToRGBGamma(x, y, DefaultGamma)
+}
 
+// chromaticityToRGBGamma converts the given xy chromaticity to normalized sRGB
+// channels corrected with the given gamma.
+func chromaticityToRGBGamma(x, y, gamma float64) (r, g, b float64) {
 	z := 1.0 - x - y
 
-	rw, gw, bw = xyzToSRGB(x, y, z)
-	rw, gw, bw = srgbNormalize(rw, gw, bw)
+	r, g, b = xyzToSRGB(x, y, z, gamma)
+	r, g, b = srgbNormalize(r, g, b)
 	return
 }
@@ -2,6 +2,7 @@ package solar
 
 import (
 	"fmt"
+	"image/color"
 	"math"
 	"testing"
 	"time"
@@ -31,15 +32,16 @@ const epochDay = 86400
 func TestCalculateSun(t *testing.T) {
 	t.Run("PDT", func(t *testing.T) {
 		// This is the same day as in the DST test but subtracted 1 day off,
-		// which was before DST was switched off.
+		// which was before DST was switched off. The events are about an hour
+		// later on the clock than the next days.
 		ts := time.Unix(1636333967-epochDay, 0)
 		ts = ts.In(losAngeles)
 
 		exp := Sun{
-			Dawn:    timeIn(t, ts, "05:26:25"),
-			Sunrise: timeIn(t, ts, "06:08:41"),
-			Sunset:  timeIn(t, ts, "16:19:56"),
-			Dusk:    timeIn(t, ts, "17:02:12"),
+			Dawn:    timeIn(t, ts, "06:47:02"),
+			Sunrise: timeIn(t, ts, "07:32:45"),
+			Sunset:  timeIn(t, ts, "17:40:29"),
+			Dusk:    timeIn(t, ts, "18:26:12"),
 		}
 
 		assertSun(t, ts, exp)
@@ -55,14 +57,17 @@ func TestCalculateSun(t *testing.T) {
 		ts := time.Unix(1636333967, 0)
 		ts = ts.In(losAngeles)
 
-		// Google reports (in PST) that the sunrise time is 6:19AM and sunset
-		// time is 4:54PM. The results of these are taken from the code, but
-		// they're very close to what Google has.
+		// NOAA's solar calculator reports (in PST) that the civil dawn is at
+		// 5:54AM, the sunrise is at 6:19AM, the sunset is at 4:55PM and the
+		// civil dusk is at 5:20PM. The results of these are taken from the
+		// code. Since the sunrise and sunset here are when the sun is a bit
+		// above the horizon, the day is about half an hour shorter, but the
+		// solar noon is within a few seconds of NOAA's 11:36:38AM.
 		exp := Sun{
-			Dawn:    timeIn(t, ts, "05:28:25"),
-			Sunrise: timeIn(t, ts, "06:10:36"),
-			Sunset:  timeIn(t, ts, "16:18:18"),
-			Dusk:    timeIn(t, ts, "17:00:30"),
+			Dawn:    timeIn(t, ts, "05:47:56"),
+			Sunrise: timeIn(t, ts, "06:33:45"),
+			Sunset:  timeIn(t, ts, "16:39:39"),
+			Dusk:    timeIn(t, ts, "17:25:28"),
 		}
 
 		assertSun(t, ts, exp)
@@ -75,16 +80,102 @@ func TestCalculateSun(t *testing.T) {
 		ts = ts.In(losAngeles)
 
 		exp := Sun{
-			Dawn:    timeIn(t, ts, "05:28:25"),
-			Sunrise: timeIn(t, ts, "06:10:36"),
-			Sunset:  timeIn(t, ts, "16:18:18"),
-			Dusk:    timeIn(t, ts, "17:00:30"),
+			Dawn:    timeIn(t, ts, "05:48:50"),
+			Sunrise: timeIn(t, ts, "06:34:45"),
+			Sunset:  timeIn(t, ts, "16:38:51"),
+			Dusk:    timeIn(t, ts, "17:24:45"),
 		}
 
 		assertSun(t, ts, exp)
 	})
 }
 
+func TestSolarNoon(t *testing.T) {
+	type test struct {
+		name string
+		zone string
+		lat  float64
+		long float64
+		date string
+		noon string // from NOAA's solar calculator spreadsheet equations
+	}
+
+	var tests = []test{
+		{"Los Angeles", "America/Los_Angeles", 34.05, -118.24, "2021-11-07", "11:36:38"},
+		{"Los Angeles DST", "America/Los_Angeles", 34.05, -118.24, "2021-07-01", "12:56:57"},
+		{"Greenwich", "Europe/London", 51.48, 0, "2021-02-11", "12:14:14"},
+		{"Tokyo", "Asia/Tokyo", 35.68, 139.69, "2021-03-20", "11:48:43"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			zone, err := time.LoadLocation(test.zone)
+			if err != nil {
+				t.Fatal("cannot load zone:", err)
+			}
+
+			date, err := time.ParseInLocation("2006-01-02", test.date, zone)
+			if err != nil {
+				t.Fatal("cannot parse date:", err)
+			}
+
+			expect := timeIn(t, date, test.noon)
+			noon := newSunDay(date, test.lat, test.long).noon()
+
+			if diff := noon.Sub(expect); diff < -time.Minute || diff > time.Minute {
+				t.Errorf("expected the solar noon at %s, got %s", expect, noon)
+			}
+		})
+	}
+}
+
+func TestCalculateSunEventOrder(t *testing.T) {
+	type test struct {
+		zone string
+		long float64
+	}
+
+	// Locations near the date line, where the timezone offset and longitude
+	// disagree the most.
+	var tests = []test{
+		{"Pacific/Kiritimati", -157.4},
+		{"Pacific/Kiritimati", 179.9},
+		{"Pacific/Pago_Pago", -170.7},
+		{"Pacific/Pago_Pago", 179.9},
+		{"Pacific/Auckland", 174.8},
+		{"Pacific/Auckland", -179.9},
+		{"Pacific/Tongatapu", -175.2},
+		{"Asia/Anadyr", 177.5},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%g", test.zone, test.long), func(t *testing.T) {
+			zone, err := time.LoadLocation(test.zone)
+			if err != nil {
+				t.Fatal("cannot load zone:", err)
+			}
+
+			for month := time.January; month <= time.December; month++ {
+				for _, hour := range []int{0, 12, 23} {
+					ts := time.Date(2021, month, 1, hour, 0, 0, 0, zone)
+					sun := CalculateSun(ts, -10, test.long)
+
+					events := []time.Time{sun.Dawn, sun.Sunrise, sun.Sunset, sun.Dusk}
+					for i := 1; i < len(events); i++ {
+						if !events[i].After(events[i-1]) {
+							t.Fatalf("%s: events out of order: %v", ts, sun)
+						}
+					}
+
+					if d := sun.Sunset.Sub(sun.Sunrise); d <= 0 {
+						t.Fatalf("%s: non-positive day length %s", ts, d)
+					}
+				}
+			}
+		})
+	}
+}
+
 func timeIn(t *testing.T, ts time.Time, clock string) time.Time {
 	v, err := time.Parse(sclockf, clock)
 	if err != nil {
@@ -131,6 +222,18 @@ func TestDaysInYear(t *testing.T) {
 	// non-leap
 	days = daysInYear(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
 	assert("2021", 365)
+
+	// divisible by 100 but not 400
+	days = daysInYear(time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert("1900", 365)
+
+	// divisible by 400
+	days = daysInYear(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert("2000", 366)
+
+	// the last day of the year in a zone far from UTC
+	days = daysInYear(time.Date(2024, time.December, 31, 23, 0, 0, 0, losAngeles))
+	assert("2024 in America/Los_Angeles", 366)
 }
 
 func TestTimeWithSeconds(t *testing.T) {
@@ -143,11 +246,12 @@ func TestTimeWithSeconds(t *testing.T) {
 	}
 
 	t.Run("PDT", func(t *testing.T) {
+		// The day starts at midnight standard time, which is 1AM during DST.
 		ts := time.Unix(1636333967-epochDay, 0)
 		ts = ts.In(losAngeles)
 		ts = timeTruncateDay(ts)
 		ts = timeAddSeconds(ts, 1)
-		assert(t, ts, 00, 00, 01)
+		assert(t, ts, 01, 00, 01)
 	})
 
 	t.Run("PST", func(t *testing.T) {
@@ -167,6 +271,121 @@ func TestTimeWithSeconds(t *testing.T) {
 	})
 }
 
+func TestSunEvents(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+
+	t.Run("normal", func(t *testing.T) {
+		sun := CalculateSun(ts, latitude, longitude)
+		events := sun.Events()
+
+		names := make([]string, len(events))
+		for i, event := range events {
+			names[i] = event.Name
+			if i > 0 && event.Time.Before(events[i-1].Time) {
+				t.Errorf("%s is before %s", event.Name, events[i-1].Name)
+			}
+		}
+
+		if fmt.Sprint(names) != "[dawn sunrise sunset dusk]" {
+			t.Errorf("unexpected events %v", names)
+		}
+	})
+
+	t.Run("polar night", func(t *testing.T) {
+		sun := CalculateSun(ts, 89, 0)
+		if sun.Condition != PolarNightSun {
+			t.Fatalf("expected polar night, got %s", sun.Condition)
+		}
+
+		if events := sun.Events(); len(events) != 0 {
+			t.Errorf("expected no events, got %v", events)
+		}
+	})
+}
+
+func TestSunNearestEvent(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	name, event, delta := sun.NearestEvent(sun.Sunrise.Add(5 * time.Minute))
+	if name != "sunrise" || !event.Equal(sun.Sunrise) || delta != 5*time.Minute {
+		t.Errorf("expected sunrise 5m ago, got %s at %s (%s)", name, event, delta)
+	}
+
+	name, _, delta = sun.NearestEvent(sun.Dusk.Add(-time.Minute))
+	if name != "dusk" || delta != -time.Minute {
+		t.Errorf("expected dusk in 1m, got %s (%s)", name, delta)
+	}
+
+	if name, _, _ := CalculateSun(ts, 89, 0).NearestEvent(ts); name != "" {
+		t.Errorf("expected no event during polar night, got %s", name)
+	}
+}
+
+func TestSunNextTransition(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	tests := []struct {
+		name   string
+		after  time.Time
+		expect time.Time
+	}{
+		{"midnight", timeIn(t, ts, "00:00:00"), sun.Dawn},
+		{"dawn", sun.Dawn, sun.Sunrise},
+		{"midday", timeIn(t, ts, "12:00:00"), sun.Sunset},
+		{"sunset", sun.Sunset.Add(-time.Nanosecond), sun.Sunset},
+	}
+
+	for _, test := range tests {
+		next, ok := sun.NextTransition(test.after)
+		if !ok || !next.Equal(test.expect) {
+			t.Errorf("%s: expected %s, got %s (%v)", test.name, test.expect, next, ok)
+		}
+	}
+
+	if next, ok := sun.NextTransition(sun.Dusk); ok {
+		t.Errorf("expected no transition after dusk, got %s", next)
+	}
+
+	if next, ok := CalculateSun(ts, 89, 0).NextTransition(ts); ok {
+		t.Errorf("expected no transition during polar night, got %s", next)
+	}
+}
+
+func TestSunRound(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+
+	sun := CalculateSun(ts, latitude, longitude).Round(time.Second)
+	for _, event := range []time.Time{sun.Dawn, sun.Sunrise, sun.Sunset, sun.Dusk} {
+		if ns := event.Nanosecond(); ns != 0 {
+			t.Errorf("%s: expected no nanoseconds, got %d", event, ns)
+		}
+	}
+
+	// Zero times must stay zero.
+	polar := CalculateSun(ts, 89, 0).Round(time.Second)
+	if polar.Condition == NormalSun || !polar.Dawn.IsZero() {
+		t.Errorf("expected zero dawn during %s, got %s", polar.Condition, polar.Dawn)
+	}
+}
+
+func TestTimeAddSeconds(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+
+	if got := timeAddSeconds(ts, 1.5); !got.Equal(ts.Add(1500 * time.Millisecond)) {
+		t.Errorf("expected %s, got %s", ts.Add(1500*time.Millisecond), got)
+	}
+
+	if got := timeAddSeconds(ts, 0.1); got.Sub(ts) != 100*time.Millisecond {
+		t.Errorf("expected exactly 100ms, got %s", got.Sub(ts))
+	}
+
+	if got := timeAddSeconds(ts, math.NaN()); !got.IsZero() {
+		t.Errorf("expected zero time for NaN, got %s", got)
+	}
+}
+
 func TestTimeLongitude(t *testing.T) {
 	ts := time.Unix(1636333967-epochDay, 0)
 	ts = ts.In(losAngeles)
@@ -179,6 +398,52 @@ func TestTimeLongitude(t *testing.T) {
 	}
 }
 
+func TestTimeLongitudeDST(t *testing.T) {
+	// This is during PDT, which is UTC-7.
+	ts := time.Unix(1636333967-epochDay, 0)
+	ts = ts.In(losAngeles)
+
+	if long := TimeLongitudeDST(ts, true); long != -120 {
+		t.Errorf("expected -120 with DST adjustment, got %.02f", long)
+	}
+
+	if long := TimeLongitudeDST(ts, false); long != -105 {
+		t.Errorf("expected -105 without DST adjustment, got %.02f", long)
+	}
+}
+
+func TestTimeLongitudeZone(t *testing.T) {
+	lordHowe, err := time.LoadLocation("Australia/Lord_Howe")
+	if err != nil {
+		t.Fatal("cannot load zone:", err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		long float64
+	}{
+		// UTC-8 standard time, both during and outside PDT.
+		{"PDT", time.Date(2021, time.July, 1, 12, 0, 0, 0, losAngeles), -120},
+		{"PST", time.Date(2021, time.December, 1, 12, 0, 0, 0, losAngeles), -120},
+		// UTC+10:30 standard time, with a 30 minute DST shift in summer.
+		{"Lord Howe DST", time.Date(2021, time.December, 1, 12, 0, 0, 0, lordHowe), 157.5},
+		{"Lord Howe", time.Date(2021, time.July, 1, 12, 0, 0, 0, lordHowe), 157.5},
+		{"UTC", time.Date(2021, time.July, 1, 12, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, test := range tests {
+		if long := TimeLongitudeZone(test.time); long != test.long {
+			t.Errorf("%s: expected %g, got %g", test.name, test.long, long)
+		}
+	}
+
+	// The fixed hour of TimeLongitude is off by 7.5 degrees for Lord Howe.
+	if long := TimeLongitude(tests[2].time); long == tests[2].long {
+		t.Errorf("expected TimeLongitude to be off for Lord Howe, got %g", long)
+	}
+}
+
 func TestCalcCondition(t *testing.T) {
 	asserter := func(t *testing.T, expect SunCondition) func(f1, f2 float64) {
 		return func(f1, f2 float64) {
@@ -216,6 +481,66 @@ func TestCalcCondition(t *testing.T) {
 	})
 }
 
+func TestCalculateTemperatureGated(t *testing.T) {
+	// The sun only gets to around 32 degrees at noon in December in Los
+	// Angeles.
+	noon := time.Date(2021, time.December, 21, 12, 0, 0, 0, losAngeles)
+	midnight := time.Date(2021, time.December, 21, 0, 0, 0, 0, losAngeles)
+
+	const lo, hi Temperature = 4000, 6500
+
+	if temp, _ := CalculateTemperatureGated(noon, latitude, longitude, lo, hi, 45); temp >= hi || temp <= lo {
+		t.Errorf("expected noon temperature between lo and hi with a 45 degree gate, got %.0fK", temp)
+	}
+
+	if temp, _ := CalculateTemperatureGated(noon, latitude, longitude, lo, hi, 10); temp != hi {
+		t.Errorf("expected hi at noon with a 10 degree gate, got %.0fK", temp)
+	}
+
+	if temp, _ := CalculateTemperatureGated(midnight, latitude, longitude, lo, hi, 45); temp != lo {
+		t.Errorf("expected lo at midnight, got %.0fK", temp)
+	}
+
+	expect, _ := CalculateTemperature(noon, latitude, longitude, lo, hi)
+	if temp, _ := CalculateTemperatureGated(noon, latitude, longitude, lo, hi, 0); temp != expect {
+		t.Errorf("expected no gate to match CalculateTemperature, got %.0fK", temp)
+	}
+}
+
+func TestIsInTransition(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	assert := func(name string, t2 time.Time, expect bool) {
+		t.Helper()
+		if got := IsInTransition(t2, latitude, longitude); got != expect {
+			t.Errorf("%s: expected %v, got %v", name, expect, got)
+		}
+	}
+
+	assert("midday", timeIn(t, ts, "12:00:00"), false)
+	assert("mid-dawn", sun.Dawn.Add(sun.Sunrise.Sub(sun.Dawn)/2), true)
+	assert("mid-dusk", sun.Sunset.Add(sun.Dusk.Sub(sun.Sunset)/2), true)
+	assert("deep night", timeIn(t, ts, "01:00:00"), false)
+}
+
+func TestIsAtExtreme(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	assert := func(name string, t2 time.Time, expectLow, expectHigh bool) {
+		t.Helper()
+		atLow, atHigh := IsAtExtreme(t2, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+		if atLow != expectLow || atHigh != expectHigh {
+			t.Errorf("%s: expected (%v, %v), got (%v, %v)", name, expectLow, expectHigh, atLow, atHigh)
+		}
+	}
+
+	assert("midday", timeIn(t, ts, "12:00:00"), false, true)
+	assert("midnight", timeIn(t, ts, "00:00:00"), true, false)
+	assert("mid-dawn", sun.Dawn.Add(sun.Sunrise.Sub(sun.Dawn)/2), false, false)
+}
+
 func TestCalculateWhitepoint(t *testing.T) {
 	eq := func(c1, c2 [3]float64) bool {
 		for i := range c1 {
@@ -258,6 +583,18 @@ func TestCalculateWhitepoint(t *testing.T) {
 	})
 }
 
+func TestTemperatureRGB(t *testing.T) {
+	if r, g, b := Temperature(6500).RGB(); r != 1 || g != 1 || b != 1 {
+		t.Errorf("expected 6500K to be white, got %v", rgb(r, g, b))
+	}
+
+	r, g, b := Temperature(4000).RGB()
+	r2, g2, b2 := CalculateWhitepoint(4000)
+	if rgb(r, g, b) != rgb(r2, g2, b2) {
+		t.Errorf("expected RGB to match CalculateWhitepoint, got %v", rgb(r, g, b))
+	}
+}
+
 func rgb(r, g, b float64) [3]float64 {
 	return [3]float64{r, g, b}
 }
@@ -267,3 +604,884 @@ func feq(f1, f2 float64) bool {
 	const accuracy = 1e-5
 	return math.Abs(f1-f2) <= accuracy
 }
+
+func TestSRGBRobustness(t *testing.T) {
+	inRange := func(t *testing.T, name string, v float64) {
+		t.Helper()
+		if math.IsNaN(v) || v < 0 || v > 1 {
+			t.Errorf("%s: %v out of range [0, 1]", name, v)
+		}
+	}
+
+	xyzs := [][3]float64{
+		{0, 0, 0},
+		{1, 1, 1},
+		{-1, -1, -1},
+		{1e9, 0, 0},
+		{0, 1e9, 0},
+		{0, 0, 1e9},
+		{-1e9, 1e9, -1e9},
+		{math.Inf(1), math.Inf(1), math.Inf(1)},
+		{math.Inf(-1), 0, math.Inf(1)},
+		{math.NaN(), math.NaN(), math.NaN()},
+		{math.NaN(), 1, 0},
+	}
+
+	for _, xyz := range xyzs {
+		t.Run(fmt.Sprint(xyz), func(t *testing.T) {
+			r, g, b := xyzToSRGB(xyz[0], xyz[1], xyz[2], DefaultGamma)
+			inRange(t, "r", r)
+			inRange(t, "g", g)
+			inRange(t, "b", b)
+
+			r, g, b = srgbNormalize(r, g, b)
+			inRange(t, "normalized r", r)
+			inRange(t, "normalized g", g)
+			inRange(t, "normalized b", b)
+		})
+	}
+
+	t.Run("gamma", func(t *testing.T) {
+		for _, gamma := range []float64{2.2, 1, 0.5, 0, -2.2, math.NaN(), math.Inf(1)} {
+			for _, v := range []float64{-1, 0, 0.001, 0.5, 1, 2, math.NaN(), math.Inf(1)} {
+				inRange(t, fmt.Sprintf("srgbGamma(%v, %v)", v, gamma), srgbGamma(v, gamma))
+			}
+		}
+	})
+
+	t.Run("normalize zero", func(t *testing.T) {
+		r, g, b := srgbNormalize(0, 0, 0)
+		if r != 0 || g != 0 || b != 0 {
+			t.Errorf("expected (0, 0, 0), got (%v, %v, %v)", r, g, b)
+		}
+	})
+}
+
+func TestCalculateSunCustomYear(t *testing.T) {
+	date := func(yearDay int) time.Time {
+		return time.Date(2021, time.January, yearDay, 12, 0, 0, 0, losAngeles)
+	}
+
+	earth := CalculateSunCustomYear(date(100), latitude, longitude, 365)
+	if expect := CalculateSun(date(100), latitude, longitude); earth != expect {
+		t.Errorf("expected a 365-day year to match CalculateSun:\n%v\n%v", expect, earth)
+	}
+
+	// Day 100 of a 200-day year is halfway through the orbit, which is
+	// Earth's summer solstice around day 182.
+	custom := CalculateSunCustomYear(date(100), latitude, longitude, 200).DayLength()
+	solstice := CalculateSun(date(182), latitude, longitude).DayLength()
+	if diff := absDuration(custom - solstice); diff > time.Minute {
+		t.Errorf("expected %v of daylight like on the solstice, got %v", solstice, custom)
+	}
+
+	if spring := CalculateSun(date(100), latitude, longitude).DayLength(); custom <= spring {
+		t.Errorf("expected the custom year to be further into summer, got %v <= %v", custom, spring)
+	}
+}
+
+func TestCalculateSunDSTDays(t *testing.T) {
+	type test struct {
+		name      string
+		zone      string
+		lat, long float64
+		date      time.Time // in UTC, only the date is used
+	}
+
+	date := func(m time.Month, d int) time.Time {
+		return time.Date(2021, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	var tests = []test{
+		{"spring forward", "America/Los_Angeles", 34.1, -118.2, date(time.March, 14)},
+		{"fall back", "America/Los_Angeles", 34.1, -118.2, date(time.November, 7)},
+		{"spring forward", "Europe/Berlin", 52.5, 13.4, date(time.March, 28)},
+		{"fall back", "Europe/Berlin", 52.5, 13.4, date(time.October, 31)},
+		{"spring forward", "Australia/Sydney", -33.9, 151.2, date(time.October, 3)},
+		{"fall back", "Australia/Sydney", -33.9, 151.2, date(time.April, 4)},
+		// Chile switches at midnight, so the day starts at 01:00.
+		{"spring forward", "America/Santiago", -33.4, -70.6, date(time.September, 5)},
+		{"fall back", "America/Santiago", -33.4, -70.6, date(time.April, 4)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.zone+"/"+test.name, func(t *testing.T) {
+			zone, err := time.LoadLocation(test.zone)
+			if err != nil {
+				t.Fatal("cannot load zone:", err)
+			}
+
+			at := func(days, hour int) time.Time {
+				y, m, d := test.date.Date()
+				return time.Date(y, m, d+days, hour, 0, 0, 0, zone)
+			}
+
+			// The results must not depend on the time of the day.
+			expect := CalculateSun(at(0, 12), test.lat, test.long)
+			for hour := 0; hour < 24; hour++ {
+				ts := at(0, hour)
+				if ts.Day() != test.date.Day() {
+					// This hour is skipped by the clock change.
+					continue
+				}
+
+				if sun := CalculateSun(ts, test.lat, test.long); sun != expect {
+					t.Errorf("%02d:00: expected %v, got %v", hour, expect, sun)
+				}
+			}
+
+			// The events must be about 24 hours apart from the days before
+			// and after, regardless of the clock change.
+			for _, days := range []int{-1, +1} {
+				sun := CalculateSun(at(days, 12), test.lat, test.long)
+
+				events := [][2]time.Time{
+					{expect.Sunrise, sun.Sunrise},
+					{expect.Sunset, sun.Sunset},
+				}
+
+				for _, event := range events {
+					diff := event[1].Sub(event[0])*time.Duration(days) - 24*time.Hour
+					if absDuration(diff) > 5*time.Minute {
+						t.Errorf("day %+d: %v and %v are not 24 hours apart", days, event[0], event[1])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTemperatureClampTo(t *testing.T) {
+	tests := []struct {
+		in      Temperature
+		out     Temperature
+		clamped bool
+	}{
+		{3000, 4000, true},
+		{4000, 4000, false},
+		{5000, 5000, false},
+		{6500, 6500, false},
+		{9000, 6500, true},
+	}
+
+	for _, test := range tests {
+		out, clamped := test.in.ClampTo(DefaultLowTemperature, DefaultHighTemperature)
+		if out != test.out || clamped != test.clamped {
+			t.Errorf("%.0fK: expected (%.0fK, %v), got (%.0fK, %v)", test.in, test.out, test.clamped, out, clamped)
+		}
+	}
+
+	if out, _ := Temperature(9000).ClampTo(DefaultHighTemperature, DefaultLowTemperature); out != DefaultHighTemperature {
+		t.Errorf("expected a swapped band to still clamp to 6500K, got %.0fK", out)
+	}
+}
+
+func TestDegreesRadians(t *testing.T) {
+	if !feq(Radians(180), math.Pi) {
+		t.Errorf("expected 180° to be π, got %f", Radians(180))
+	}
+	if !feq(Degrees(math.Pi/2), 90) {
+		t.Errorf("expected π/2 to be 90°, got %f", Degrees(math.Pi/2))
+	}
+
+	for _, deg := range []float64{-270, -90.833, 0, 23.44, 90, 359.9} {
+		if rt := Degrees(Radians(deg)); !feq(rt, deg) {
+			t.Errorf("%g: round trip returned %g", deg, rt)
+		}
+	}
+}
+
+func TestBlueHourTemperature(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	lo, hi := DefaultLowTemperature, DefaultHighTemperature
+
+	min, max := BlueHourTemperature(date, latitude, longitude, lo, hi)
+	t.Logf("blue hour: %.0fK to %.0fK", min, max)
+
+	if min < lo || max > hi || min > max {
+		t.Fatalf("unexpected blue hour range %.0fK to %.0fK", min, max)
+	}
+
+	// The golden hour is between 4 degrees below and 6 degrees above the
+	// horizon, so it should be closer to hi.
+	goldenMin, goldenMax := temperatureRangeBetween(date, latitude, longitude, lo, hi, -4, 6)
+	t.Logf("golden hour: %.0fK to %.0fK", goldenMin, goldenMax)
+
+	if (min+max)/2 >= (goldenMin+goldenMax)/2 {
+		t.Errorf("expected the blue hour to be closer to lo than the golden hour")
+	}
+	if min-lo > hi-max {
+		t.Errorf("expected the blue hour to skew towards lo")
+	}
+
+	if min, max := BlueHourTemperature(date, 89, 0, lo, hi); min != 0 || max != 0 {
+		t.Errorf("expected no blue hour during polar night, got %.0fK to %.0fK", min, max)
+	}
+}
+
+func TestInterpolateSun(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	a := CalculateSun(ts, latitude, longitude)
+	b := CalculateSun(ts.AddDate(0, 0, 1), latitude, longitude)
+
+	if sun := InterpolateSun(a, b, 0); sun != a {
+		t.Errorf("frac 0: expected %v, got %v", a, sun)
+	}
+	if sun := InterpolateSun(a, b, 1); sun != b {
+		t.Errorf("frac 1: expected %v, got %v", b, sun)
+	}
+
+	mid := InterpolateSun(a, b, 0.5)
+	for _, event := range []struct {
+		name      string
+		a, mid, b time.Time
+	}{
+		{"dawn", a.Dawn, mid.Dawn, b.Dawn},
+		{"sunrise", a.Sunrise, mid.Sunrise, b.Sunrise},
+		{"sunset", a.Sunset, mid.Sunset, b.Sunset},
+		{"dusk", a.Dusk, mid.Dusk, b.Dusk},
+	} {
+		if !event.mid.After(event.a) || !event.mid.Before(event.b) {
+			t.Errorf("%s: expected %v to be between %v and %v", event.name, event.mid, event.a, event.b)
+		}
+	}
+
+	t.Run("condition change", func(t *testing.T) {
+		normal := Sun{Condition: NormalSun, Sunrise: a.Sunrise, Sunset: a.Sunset}
+		polar := Sun{Condition: PolarNightSun}
+
+		if sun := InterpolateSun(normal, polar, 0.25); sun != normal {
+			t.Errorf("expected the normal sun, got %v", sun)
+		}
+		if sun := InterpolateSun(normal, polar, 0.75); sun != polar {
+			t.Errorf("expected the polar night, got %v", sun)
+		}
+	})
+}
+
+func TestNextTransitionTime(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+	today := CalculateSun(ts, latitude, longitude)
+	tomorrow := CalculateSun(ts.AddDate(0, 0, 1), latitude, longitude)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		expect time.Time
+		kind   string
+	}{
+		{"midnight", ts, today.Dawn, "dawn"},
+		{"at dawn", today.Dawn, today.Sunrise, "sunrise"},
+		{"midday", timeIn(t, ts, "12:00:00"), today.Sunset, "sunset"},
+		{"mid-dusk", today.Sunset.Add(time.Minute), today.Dusk, "dusk"},
+		{"after dusk", today.Dusk.Add(time.Minute), tomorrow.Dawn, "dawn"},
+	}
+
+	for _, test := range tests {
+		next, kind := NextTransitionTime(test.at, latitude, longitude)
+		if !next.Equal(test.expect) || kind != test.kind {
+			t.Errorf("%s: expected %s at %v, got %s at %v", test.name, test.kind, test.expect, kind, next)
+		}
+	}
+
+	t.Run("polar night", func(t *testing.T) {
+		// Tromsø's polar night ends in mid January.
+		ts := time.Date(2021, time.December, 21, 12, 0, 0, 0, time.UTC)
+
+		next, kind := NextTransitionTime(ts, 69.6, 18.9)
+		if next.IsZero() || kind != "dawn" {
+			t.Fatalf("expected a dawn after the polar night, got %q at %v", kind, next)
+		}
+		if next.Before(ts.AddDate(0, 0, 7)) || next.After(ts.AddDate(0, 2, 0)) {
+			t.Errorf("expected the polar night to end in a few weeks, got %v", next)
+		}
+	})
+
+	t.Run("north pole", func(t *testing.T) {
+		ts := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+		if next, kind := NextTransitionTime(ts, 90, 0); !next.IsZero() {
+			t.Errorf("expected no transition at the pole, got %q at %v", kind, next)
+		}
+	})
+}
+
+func TestCalculateSunWithAngles(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+
+	for _, angle := range []struct {
+		deg  float64
+		dawn func(time.Time, float64, float64) (time.Time, error)
+		dusk func(time.Time, float64, float64) (time.Time, error)
+	}{
+		{6, CivilDawn, CivilDusk},
+		{12, NauticalDawn, NauticalDusk},
+		{18, AstronomicalDawn, AstronomicalDusk},
+	} {
+		sun := CalculateSunWithAngles(ts, latitude, longitude, angle.deg, angle.deg)
+
+		dawn, _ := angle.dawn(ts, latitude, longitude)
+		dusk, _ := angle.dusk(ts, latitude, longitude)
+		if !sun.Dawn.Equal(dawn) || !sun.Dusk.Equal(dusk) {
+			t.Errorf("%v degrees: expected dawn %v and dusk %v, got %v and %v",
+				angle.deg, dawn, dusk, sun.Dawn, sun.Dusk)
+		}
+	}
+
+	civil := CalculateSunWithAngles(ts, latitude, longitude, 6, 6)
+	nautical := CalculateSunWithAngles(ts, latitude, longitude, 12, 12)
+	astronomical := CalculateSunWithAngles(ts, latitude, longitude, 18, 18)
+
+	if !astronomical.Dawn.Before(nautical.Dawn) || !nautical.Dawn.Before(civil.Dawn) {
+		t.Errorf("expected deeper dawns to be earlier: %v, %v, %v", astronomical.Dawn, nautical.Dawn, civil.Dawn)
+	}
+	if !astronomical.Dusk.After(nautical.Dusk) || !nautical.Dusk.After(civil.Dusk) {
+		t.Errorf("expected deeper dusks to be later: %v, %v, %v", astronomical.Dusk, nautical.Dusk, civil.Dusk)
+	}
+	if nautical.Sunrise != civil.Sunrise || nautical.Sunset != civil.Sunset {
+		t.Errorf("expected the sunrise and sunset to not change")
+	}
+
+	t.Run("white night", func(t *testing.T) {
+		// At 55N in the summer, the sun gets 6 but not 18 degrees below the
+		// horizon.
+		ts := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+		sun := CalculateSunWithAngles(ts, 55, 0, 6, 18)
+		if sun.Dawn.IsZero() {
+			t.Error("expected the civil dawn to exist")
+		}
+		if !sun.Dusk.IsZero() {
+			t.Errorf("expected no astronomical dusk, got %v", sun.Dusk)
+		}
+		if sun.Condition != MidnightSun {
+			t.Errorf("expected midnight sun, got %s", sun.Condition)
+		}
+	})
+}
+
+func TestCheckLongitudeConsistency(t *testing.T) {
+	ts := time.Date(2021, time.July, 1, 12, 0, 0, 0, losAngeles)
+
+	offBy, ok := CheckLongitudeConsistency(ts, longitude)
+	if !ok {
+		t.Errorf("expected Los Angeles to be consistent with its zone, off by %v", offBy)
+	}
+	if expect := -8*time.Hour + time.Duration(118.2/15*float64(time.Hour)); absDuration(offBy-expect) > time.Second {
+		t.Errorf("expected to be off by %v, got %v", expect, offBy)
+	}
+
+	offBy, ok = CheckLongitudeConsistency(ts, 0)
+	if ok {
+		t.Errorf("expected longitude 0 to be inconsistent with UTC-8")
+	}
+	if offBy != -8*time.Hour {
+		t.Errorf("expected to be off by -8h, got %v", offBy)
+	}
+}
+
+func TestCalculateSunDeepTwilight(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	order := []time.Time{
+		sun.AstronomicalDawn, sun.NauticalDawn, sun.Dawn, sun.Sunrise,
+		sun.Sunset, sun.Dusk, sun.NauticalDusk, sun.AstronomicalDusk,
+	}
+	for i := 1; i < len(order); i++ {
+		if !order[i].After(order[i-1]) {
+			t.Errorf("event %d at %v is not after %v", i, order[i], order[i-1])
+		}
+	}
+
+	nautical, _ := NauticalDawn(ts, latitude, longitude)
+	if !sun.NauticalDawn.Equal(nautical) {
+		t.Errorf("expected the nautical dawn to match NauticalDawn %v, got %v", nautical, sun.NauticalDawn)
+	}
+
+	t.Run("white night", func(t *testing.T) {
+		ts := time.Date(2021, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+		sun := CalculateSun(ts, 55, 0)
+		if sun.Condition != NormalSun {
+			t.Errorf("expected the condition to stay normal, got %s", sun.Condition)
+		}
+		if !sun.AstronomicalDawn.IsZero() || !sun.AstronomicalDusk.IsZero() {
+			t.Errorf("expected no astronomical twilight, got %v and %v", sun.AstronomicalDawn, sun.AstronomicalDusk)
+		}
+		if len(sun.Events()) != 4 {
+			t.Errorf("expected the 4 normal events, got %v", sun.Events())
+		}
+	})
+}
+
+func TestTemperatureColor(t *testing.T) {
+	if c := Temperature(6500).Color(); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("expected 6500K to be opaque white, got %v", c)
+	}
+
+	c := Temperature(4000).Color().(color.NRGBA)
+	if c.A != 255 || c.R != 255 || c.B >= c.G || c.G >= c.R {
+		t.Errorf("expected 4000K to be an opaque warm color, got %v", c)
+	}
+}
+
+func TestCalculateSunGeometric(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+
+	sun := CalculateSun(ts, latitude, longitude)
+	geo := CalculateSunGeometric(ts, latitude, longitude)
+
+	if geo.Condition != NormalSun {
+		t.Fatalf("expected normal sun, got %s", geo.Condition)
+	}
+
+	rise := geo.Sunrise.Sub(sun.Sunrise)
+	set := sun.Sunset.Sub(geo.Sunset)
+	t.Logf("geometric sunrise is %v later, sunset is %v earlier", rise, set)
+
+	for name, d := range map[string]time.Duration{"sunrise": rise, "sunset": set} {
+		if d <= 0 || d > 10*time.Minute {
+			t.Errorf("%s: expected the geometric horizon to be a few minutes off, got %v", name, d)
+		}
+	}
+
+	if !geo.Dawn.After(sun.Dawn) || !geo.Dusk.Before(sun.Dusk) {
+		t.Errorf("expected the geometric twilight to be shorter")
+	}
+}
+
+func TestSunIsDaytime(t *testing.T) {
+	sun := CalculateSun(time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles), latitude, longitude)
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		daytime  bool
+		twilight bool
+	}{
+		{"night", sun.Dawn.Add(-time.Minute), false, false},
+		{"dawn", sun.Dawn, false, true},
+		{"sunrise", sun.Sunrise, true, false},
+		{"noon", sun.Sunrise.Add(sun.DayLength() / 2), true, false},
+		{"sunset", sun.Sunset, false, true},
+		{"dusk", sun.Dusk, false, false},
+	}
+
+	for _, test := range tests {
+		if got := sun.IsDaytime(test.time); got != test.daytime {
+			t.Errorf("%s: expected IsDaytime %v, got %v", test.name, test.daytime, got)
+		}
+		if got := sun.IsTwilight(test.time); got != test.twilight {
+			t.Errorf("%s: expected IsTwilight %v, got %v", test.name, test.twilight, got)
+		}
+	}
+
+	june := time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC)
+
+	midnight := CalculateSun(june, 85, 0)
+	if !midnight.IsDaytime(june) || midnight.IsTwilight(june) {
+		t.Errorf("expected the midnight sun to always be daytime")
+	}
+
+	polar := CalculateSun(june, -85, 0)
+	if polar.IsDaytime(june.Add(12*time.Hour)) || polar.IsTwilight(june.Add(12*time.Hour)) {
+		t.Errorf("expected the polar night to never be daytime")
+	}
+}
+
+func TestCalculateTemperaturePlateau(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	sun := CalculateSun(date, latitude, longitude)
+	noon := sun.Sunrise.Add(sun.DayLength() / 2)
+
+	const lo, hi Temperature = 4000, 6500
+
+	if temp, _ := CalculateTemperaturePlateau(noon, latitude, longitude, lo, hi, time.Hour); temp != hi {
+		t.Errorf("expected hi at noon, got %.0fK", temp)
+	}
+
+	afternoon := noon.Add(2 * time.Hour)
+	if temp, _ := CalculateTemperaturePlateau(afternoon, latitude, longitude, lo, hi, time.Hour); temp >= hi || temp <= lo {
+		t.Errorf("expected the afternoon temperature between lo and hi, got %.0fK", temp)
+	}
+
+	if temp, _ := CalculateTemperaturePlateau(sun.Dusk, latitude, longitude, lo, hi, time.Hour); temp != lo {
+		t.Errorf("expected lo at dusk, got %.0fK", temp)
+	}
+
+	expect, _ := CalculateTemperature(afternoon, latitude, longitude, lo, hi)
+	if temp, _ := CalculateTemperaturePlateau(afternoon, latitude, longitude, lo, hi, 24*time.Hour); temp != expect {
+		t.Errorf("expected a whole day plateau to match CalculateTemperature, got %.0fK", temp)
+	}
+}
+
+func TestCalculateSunAtElevation(t *testing.T) {
+	ts := time.Date(2021, time.November, 8, 12, 0, 0, 0, losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	if got := CalculateSunAtElevation(ts, latitude, longitude, 0); got != sun {
+		t.Errorf("expected sea level to match CalculateSun, got %s", got)
+	}
+
+	high := CalculateSunAtElevation(ts, latitude, longitude, 1000)
+	earlier := sun.Sunrise.Sub(high.Sunrise)
+	later := high.Sunset.Sub(sun.Sunset)
+	t.Logf("at 1000m, sunrise is %v earlier and sunset is %v later", earlier, later)
+
+	for name, d := range map[string]time.Duration{"sunrise": earlier, "sunset": later} {
+		if d < 3*time.Minute || d > 15*time.Minute {
+			t.Errorf("%s: expected several minutes of difference, got %v", name, d)
+		}
+	}
+
+	if !high.Dawn.Equal(sun.Dawn) || !high.Dusk.Equal(sun.Dusk) {
+		t.Errorf("expected the twilight to be unaffected")
+	}
+}
+
+func TestCalculateTemperatureAnchored(t *testing.T) {
+	// Dawn is around 05:06 in June in Los Angeles, long before the anchor.
+	date := time.Date(2021, time.June, 21, 0, 0, 0, 0, losAngeles)
+	sun := CalculateSun(date, latitude, longitude)
+
+	const lo, hi Temperature = 4000, 6500
+	const morning, evening = 7 * time.Hour, 20 * time.Hour
+
+	anchor := date.Add(morning)
+	if !sun.Dawn.Before(anchor.Add(-time.Hour)) {
+		t.Fatalf("expected dawn to be much earlier than the anchor, got %s", sun.Dawn)
+	}
+
+	calc := func(t time.Time) Temperature {
+		temp, _ := CalculateTemperatureAnchored(t, latitude, longitude, lo, hi, morning, evening)
+		return temp
+	}
+
+	if temp := calc(sun.Sunrise); temp != lo {
+		t.Errorf("expected lo at sunrise before the anchor, got %.0fK", temp)
+	}
+	if temp := calc(anchor.Add(-time.Minute)); temp != lo {
+		t.Errorf("expected lo right before the anchor, got %.0fK", temp)
+	}
+	if temp := calc(anchor.Add(10 * time.Minute)); temp <= lo || temp >= hi {
+		t.Errorf("expected the ramp to start at the anchor, got %.0fK", temp)
+	}
+	if temp := calc(date.Add(12 * time.Hour)); temp != hi {
+		t.Errorf("expected hi at noon, got %.0fK", temp)
+	}
+	if temp := calc(date.Add(evening)); temp != lo {
+		t.Errorf("expected lo at the evening anchor, got %.0fK", temp)
+	}
+
+	expect, _ := CalculateTemperature(sun.Sunrise, latitude, longitude, lo, hi)
+	if temp, _ := CalculateTemperatureAnchored(sun.Sunrise, latitude, longitude, lo, hi, 0, 0); temp != expect {
+		t.Errorf("expected no anchors to match CalculateTemperature, got %.0fK", temp)
+	}
+}
+
+func TestCalculateSunFast(t *testing.T) {
+	ts := time.Date(2021, time.June, 21, 12, 0, 0, 0, losAngeles)
+
+	var lats []float64
+	for lat := -89.5; lat < 90; lat += 0.5 {
+		lats = append(lats, lat)
+	}
+
+	suns := CalculateSunFast(ts, lats, longitude)
+	if len(suns) != len(lats) {
+		t.Fatalf("expected %d suns, got %d", len(lats), len(suns))
+	}
+
+	for i, lat := range lats {
+		fast := suns[i]
+		exact := CalculateSun(ts, lat, longitude)
+
+		if fast.Condition != exact.Condition {
+			t.Errorf("%g: expected condition %s, got %s", lat, exact.Condition, fast.Condition)
+			continue
+		}
+
+		events := [][2]time.Time{
+			{fast.Dawn, exact.Dawn},
+			{fast.Sunrise, exact.Sunrise},
+			{fast.Sunset, exact.Sunset},
+			{fast.Dusk, exact.Dusk},
+		}
+
+		for _, event := range events {
+			if event[0].IsZero() != event[1].IsZero() {
+				t.Errorf("%g: expected %s, got %s", lat, event[1], event[0])
+				continue
+			}
+			if diff := event[0].Sub(event[1]); diff < -time.Minute || diff > time.Minute {
+				t.Errorf("%g: expected %s within a minute, got %s", lat, event[1], event[0])
+			}
+		}
+	}
+}
+
+func BenchmarkCalculateSunFast(b *testing.B) {
+	ts := time.Date(2021, time.June, 21, 12, 0, 0, 0, losAngeles)
+
+	lats := make([]float64, 1000)
+	for i := range lats {
+		lats[i] = -60 + 120*float64(i)/float64(len(lats))
+	}
+
+	b.Run("fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			CalculateSunFast(ts, lats, longitude)
+		}
+	})
+
+	b.Run("exact", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, lat := range lats {
+				CalculateSun(ts, lat, longitude)
+			}
+		}
+	})
+}
+
+func TestCalculateWhitepointGamma(t *testing.T) {
+	const temp = 3000
+
+	r, g, b := CalculateWhitepoint(temp)
+	if c := rgb(CalculateWhitepointGamma(temp, 2.2)); c != rgb(r, g, b) {
+		t.Errorf("expected gamma 2.2 to match CalculateWhitepoint, got %v", c)
+	}
+
+	linear := rgb(CalculateWhitepointGamma(temp, 1))
+	srgb := rgb(CalculateWhitepointGamma(temp, 2.4))
+	t.Log("linear:", linear, "2.2:", rgb(r, g, b), "2.4:", srgb)
+
+	// Gamma correction brightens the dimmer channels, more so with a higher
+	// gamma.
+	if !(linear[1] < g && g < srgb[1]) || !(linear[2] < b && b < srgb[2]) {
+		t.Errorf("expected the green and blue to brighten with the gamma")
+	}
+
+	// The linear channels are the XYZ to RGB matrix on its own.
+	x, y := whitepointChromaticity(temp)
+	z := 1 - x - y
+	expect := rgb(srgbNormalize(
+		clamp((3.2404542*x)-(1.5371385*y)-(0.4985314*z)),
+		clamp((-0.9692660*x)+(1.8760108*y)+(0.0415560*z)),
+		clamp((0.0556434*x)-(0.2040259*y)+(1.0572252*z)),
+	))
+	if linear != expect {
+		t.Errorf("expected the linear whitepoint %v, got %v", expect, linear)
+	}
+
+	if c := rgb(CalculateWhitepointGamma(6500, 1)); c != rgb(1, 1, 1) {
+		t.Errorf("expected white at 6500K, got %v", c)
+	}
+}
+
+func TestSunFromOrbitAngle(t *testing.T) {
+	for _, ts := range []time.Time{
+		time.Unix(1636333967, 0).In(losAngeles),
+		time.Date(2024, time.February, 29, 12, 0, 0, 0, losAngeles),
+		time.Date(2021, time.June, 21, 0, 0, 0, 0, time.UTC),
+	} {
+		orbitAngle := dateOrbitAngle(ts, float64(daysInYear(ts)))
+		if sun, expect := SunFromOrbitAngle(orbitAngle, latitude, longitude, ts), CalculateSun(ts, latitude, longitude); sun != expect {
+			t.Errorf("%s: expected %v, got %v", ts, expect, sun)
+		}
+	}
+}
+
+func TestCalculateSunUTC(t *testing.T) {
+	date := time.Date(2021, time.November, 3, 18, 0, 0, 0, losAngeles)
+	sun := CalculateSunUTC(date, latitude)
+
+	if sun.Condition != NormalSun {
+		t.Fatalf("expected a normal sun, got %s", sun.Condition)
+	}
+
+	for _, event := range []time.Time{sun.Dawn, sun.Sunrise, sun.Sunset, sun.Dusk} {
+		if event.Location() != time.UTC {
+			t.Errorf("%s is not in UTC", event)
+		}
+		if event.Day() != 3 {
+			t.Errorf("expected %s to be on the 3rd", event)
+		}
+	}
+
+	// The equation of time is at its maximum of about 16m25s in early
+	// November, so the solar noon is that much before 12:00.
+	noon := MidpointBetween(sun.Sunrise, sun.Sunset)
+	expect := time.Date(2021, time.November, 3, 11, 43, 35, 0, time.UTC)
+	if diff := noon.Sub(expect); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("expected the solar noon at %s, got %s", expect.Format(sclockf), noon.Format(sclockf))
+	}
+
+	// The sun is symmetric around the solar noon.
+	if rise, set := noon.Sub(sun.Sunrise), sun.Sunset.Sub(noon); absDuration(rise-set) > time.Millisecond {
+		t.Errorf("expected the sunrise and sunset around the noon, got %v and %v", rise, set)
+	}
+}
+
+func TestEquationOfTime(t *testing.T) {
+	type test struct {
+		date   time.Time
+		expect time.Duration
+	}
+
+	// The approximate extremes of the equation of time.
+	var tests = []test{
+		{time.Date(2021, time.February, 11, 12, 0, 0, 0, time.UTC), -14*time.Minute - 14*time.Second},
+		{time.Date(2021, time.May, 14, 12, 0, 0, 0, time.UTC), 3*time.Minute + 39*time.Second},
+		{time.Date(2021, time.July, 26, 12, 0, 0, 0, time.UTC), -6*time.Minute - 32*time.Second},
+		{time.Date(2021, time.November, 3, 12, 0, 0, 0, time.UTC), 16*time.Minute + 25*time.Second},
+	}
+
+	for _, test := range tests {
+		eot := EquationOfTime(test.date)
+		if diff := eot - test.expect; diff < -30*time.Second || diff > 30*time.Second {
+			t.Errorf("%s: expected about %v, got %v", test.date.Format("2006-01-02"), test.expect, eot)
+		}
+	}
+}
+
+func TestCalculateTemperatureFunc(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	const lo, hi Temperature = 4000, 6500
+
+	// A quarter into the sunset, where the eases differ the most from linear.
+	quarter := sun.Sunset.Add(sun.Dusk.Sub(sun.Sunset) / 4)
+
+	linear, _ := CalculateTemperature(quarter, latitude, longitude, lo, hi)
+	if temp, _ := CalculateTemperatureFunc(quarter, latitude, longitude, lo, hi, nil); temp != linear {
+		t.Errorf("expected a nil ease to be linear %.0fK, got %.0fK", linear, temp)
+	}
+	if temp, _ := CalculateTemperatureFunc(quarter, latitude, longitude, lo, hi, EaseLinear); temp != linear {
+		t.Errorf("expected EaseLinear to be linear %.0fK, got %.0fK", linear, temp)
+	}
+
+	for name, ease := range map[string]func(float64) float64{
+		"cosine":     EaseCosine,
+		"smoothstep": EaseSmoothstep,
+	} {
+		if v0, v1, mid := ease(0), ease(1), ease(0.5); !feq(v0, 0) || !feq(v1, 1) || !feq(mid, 0.5) {
+			t.Errorf("%s: expected 0, 0.5 and 1, got %v, %v and %v", name, v0, mid, v1)
+		}
+
+		temp, _ := CalculateTemperatureFunc(quarter, latitude, longitude, lo, hi, ease)
+		// The ease starts slowly, so it is still closer to hi.
+		if temp <= linear || temp >= hi {
+			t.Errorf("%s: expected between %.0fK and %.0fK, got %.0fK", name, linear, hi, temp)
+		}
+
+		for _, at := range []time.Time{sun.Dawn.Add(-time.Minute), timeIn(t, ts, "12:00:00"), sun.Dusk} {
+			expect, _ := CalculateTemperature(at, latitude, longitude, lo, hi)
+			if temp, _ := CalculateTemperatureFunc(at, latitude, longitude, lo, hi, ease); temp != expect {
+				t.Errorf("%s at %s: expected %.0fK outside of the transitions, got %.0fK", name, at, expect, temp)
+			}
+		}
+	}
+}
+
+func TestCalculateTemperatureDuration(t *testing.T) {
+	// Near the equator, the twilight is only around 20 minutes.
+	const lat, long = 1.3521, 103.8198
+	singapore := time.FixedZone("SGT", 8*60*60)
+
+	date := time.Date(2021, time.March, 20, 0, 0, 0, 0, singapore)
+	sun := CalculateSun(date, lat, long)
+
+	const lo, hi Temperature = 4000, 6500
+	const ramp = 2 * time.Hour
+
+	if twilight := sun.Sunrise.Sub(sun.Dawn); twilight >= ramp/2 {
+		t.Fatalf("expected a short twilight, got %s", twilight)
+	}
+
+	calc := func(t time.Time) Temperature {
+		temp, _ := CalculateTemperatureDuration(t, lat, long, lo, hi, ramp)
+		return temp
+	}
+
+	mid := lo + (hi-lo)/2
+	if temp := calc(sun.Sunrise); !feq(float64(temp), float64(mid)) {
+		t.Errorf("expected %.0fK at sunrise, got %.0fK", mid, temp)
+	}
+	if temp := calc(sun.Sunset); !feq(float64(temp), float64(mid)) {
+		t.Errorf("expected %.0fK at sunset, got %.0fK", mid, temp)
+	}
+	if temp := calc(sun.Sunrise.Add(-ramp/2 - time.Minute)); temp != lo {
+		t.Errorf("expected lo before the ramp, got %.0fK", temp)
+	}
+	if temp := calc(sun.Dawn); temp <= lo || temp >= mid {
+		t.Errorf("expected the ramp to have started by dawn, got %.0fK", temp)
+	}
+	if temp := calc(sun.Sunrise.Add(ramp/2 + time.Minute)); temp != hi {
+		t.Errorf("expected hi after the ramp, got %.0fK", temp)
+	}
+	if temp := calc(sun.Sunset.Add(ramp/2 + time.Minute)); temp != lo {
+		t.Errorf("expected lo after the ramp, got %.0fK", temp)
+	}
+
+	// A ramp longer than the day never reaches hi.
+	noon := sun.Sunrise.Add(sun.DayLength() / 2)
+	if temp, _ := CalculateTemperatureDuration(noon, lat, long, lo, hi, 24*time.Hour); temp <= mid || temp >= hi {
+		t.Errorf("expected a long ramp to peak below hi at noon, got %.0fK", temp)
+	}
+
+	if temp, _ := CalculateTemperatureDuration(sun.Sunrise.Add(-time.Second), lat, long, lo, hi, 0); temp != lo {
+		t.Errorf("expected no ramp to be lo before sunrise, got %.0fK", temp)
+	}
+	if temp, _ := CalculateTemperatureDuration(sun.Sunrise, lat, long, lo, hi, 0); temp != hi {
+		t.Errorf("expected no ramp to be hi at sunrise, got %.0fK", temp)
+	}
+}
+
+func TestMismatchedZone(t *testing.T) {
+	const lat = 10
+
+	// With a UTC clock, the solar days at these longitudes start many hours
+	// away from midnight.
+	for _, long := range []float64{179, 150, 100, -100, -150, -179} {
+		calc := NewSunCalculator(lat, long)
+
+		start := time.Date(2021, time.November, 7, 0, 0, 0, 0, time.UTC)
+		for ts := start; ts.Before(start.AddDate(0, 0, 2)); ts = ts.Add(10 * time.Minute) {
+			const lo, hi Temperature = 4000, 6500
+
+			temp, _ := CalculateTemperature(ts, lat, long, lo, hi)
+			if calcTemp, _ := calc.Temperature(ts, lo, hi); calcTemp != temp {
+				t.Fatalf("%g at %s: expected the calculator to match %.0fK, got %.0fK", long, ts, temp, calcTemp)
+			}
+
+			// The sunrise and sunset are when the sun is a bit above the
+			// horizon, and the dawn and dusk are a bit below 6 degrees.
+			switch elevation := SunElevation(ts, lat, long); {
+			case elevation > 3 && temp != hi:
+				t.Fatalf("%g at %s: expected hi with the sun at %.1f°, got %.0fK", long, ts, elevation, temp)
+			case elevation < -7 && temp != lo:
+				t.Fatalf("%g at %s: expected lo with the sun at %.1f°, got %.0fK", long, ts, elevation, temp)
+			case elevation > -6 && elevation < 2 && (temp == lo || temp == hi):
+				t.Fatalf("%g at %s: expected a transition with the sun at %.1f°, got %.0fK", long, ts, elevation, temp)
+			}
+
+			if inTransition := IsInTransition(ts, lat, long); inTransition != (temp != lo && temp != hi) {
+				t.Fatalf("%g at %s: expected IsInTransition to agree with %.0fK, got %v", long, ts, temp, inTransition)
+			}
+		}
+	}
+
+	// The sun sets at around 05:18 and the dusk ends at around 05:56.
+	ts := time.Date(2021, time.November, 8, 5, 0, 0, 0, time.UTC)
+
+	next, name := NextTransitionTime(ts, lat, -179)
+	if name != "sunset" || next.Sub(ts) > time.Hour {
+		t.Errorf("expected the sunset within the hour, got the %s at %s", name, next)
+	}
+
+	remaining, _ := DaylightRemaining(ts, lat, -179)
+	if remaining <= 0 || remaining > time.Hour {
+		t.Errorf("expected the daylight to end within the hour, got %s", remaining)
+	}
+}
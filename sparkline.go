@@ -0,0 +1,46 @@
+package solar
+
+import (
+	"strings"
+	"time"
+)
+
+// sparkBlocks are the characters that TemperatureSparkline uses, from the
+// lowest to the highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// TemperatureSparkline renders the color temperature throughout the day of the
+// given date as a sparkline of the given width in characters, such as
+// "▁▁▁▃▆████████▆▃▁▁▁". Each character is a sample of the temperature at evenly
+// spaced time instants from midnight to midnight, with the lowest block being
+// lo and the highest being hi.
+func TemperatureSparkline(date time.Time, lat, long float64, lo, hi Temperature, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+	step := end.Sub(start) / time.Duration(width)
+
+	calc := NewSunCalculator(lat, long)
+
+	var b strings.Builder
+	b.Grow(width * len(string(sparkBlocks[0])))
+
+	for i := 0; i < width; i++ {
+		// Sample in the middle of each step.
+		t := start.Add(step*time.Duration(i) + step/2)
+		temp, _ := calc.Temperature(t, lo, hi)
+
+		var pos float64
+		if hi != lo {
+			pos = clamp(float64(temp-lo) / float64(hi-lo))
+		}
+
+		b.WriteRune(sparkBlocks[int(pos*float64(len(sparkBlocks)-1)+0.5)])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package solar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTemperatureSparkline(t *testing.T) {
+	date := time.Date(2021, time.November, 8, 0, 0, 0, 0, losAngeles)
+
+	const width = 48
+	line := TemperatureSparkline(date, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature, width)
+	t.Log("sparkline:", line)
+
+	if n := utf8.RuneCountInString(line); n != width {
+		t.Fatalf("expected %d characters, got %d", width, n)
+	}
+
+	blocks := []rune(line)
+	if midday := blocks[width/2]; midday != '█' {
+		t.Errorf("expected the highest block at midday, got %q", midday)
+	}
+	if midnight := blocks[0]; midnight != '▁' {
+		t.Errorf("expected the lowest block at midnight, got %q", midnight)
+	}
+
+	if !strings.ContainsAny(line, "▂▃▄▅▆▇") {
+		t.Errorf("expected the transitions to be visible, got %q", line)
+	}
+
+	if line := TemperatureSparkline(date, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature, 0); line != "" {
+		t.Errorf("expected an empty sparkline for width 0, got %q", line)
+	}
+}
@@ -0,0 +1,123 @@
+package solar
+
+import (
+	"context"
+	"time"
+)
+
+// WatchTransitionInterval is the interval that WatchCurrentTemperature
+// recalculates the temperature at while it is transitioning.
+const WatchTransitionInterval = time.Minute
+
+// WatchCurrentTemperature watches the current color temperature at the given
+// location. The returned channel receives the current temperature immediately,
+// then again at every transition as well as every WatchTransitionInterval while
+// the temperature is transitioning. The watching stops and the channel is
+// closed once ctx is done.
+//
+// The channel only ever holds the latest temperature, so a slow receiver never
+// blocks the watcher; it just misses the stale values.
+func WatchCurrentTemperature(ctx context.Context, lat, long float64, lo, hi Temperature) <-chan Temperature {
+	ch := make(chan Temperature, 1)
+
+	go func() {
+		defer close(ch)
+		watchTemperature(ctx, lat, long, lo, hi, func(temp Temperature) {
+			sendLatest(ch, temp)
+		})
+	}()
+
+	return ch
+}
+
+// WatchWhitepoint is like WatchCurrentTemperature, except the channel receives
+// the whitepoint of the temperature as calculated by CalculateWhitepoint.
+func WatchWhitepoint(ctx context.Context, lat, long float64, lo, hi Temperature) <-chan [3]float64 {
+	temps := WatchCurrentTemperature(ctx, lat, long, lo, hi)
+	ch := make(chan [3]float64)
+
+	go func() {
+		defer close(ch)
+
+		// Only the whitepoint of the latest temperature is kept, and it's only
+		// offered to the receiver once there is one, so a slow receiver still
+		// just misses the stale values.
+		var latest [3]float64
+		var send chan<- [3]float64
+
+		for {
+			select {
+			case temp, ok := <-temps:
+				if !ok {
+					return
+				}
+				r, g, b := CalculateWhitepoint(temp)
+				latest = [3]float64{r, g, b}
+				send = ch
+			case send <- latest:
+				send = nil
+			}
+		}
+	}()
+
+	return ch
+}
+
+// watchTemperature calls send with the current color temperature at the times
+// described by WatchCurrentTemperature until ctx is done.
+func watchTemperature(ctx context.Context, lat, long float64, lo, hi Temperature, send func(Temperature)) {
+	calc := NewSunCalculator(lat, long)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		temp, _ := calc.Temperature(now, lo, hi)
+		send(temp)
+
+		timer.Reset(time.Until(nextWatchTime(now, lat, long)))
+	}
+}
+
+// sendLatest sends the value into the buffered channel, replacing the value
+// that is in it if it's full. There must be only one sender.
+func sendLatest(ch chan Temperature, v Temperature) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// nextWatchTime returns the next time instant after t that the temperature
+// should be recalculated at.
+func nextWatchTime(t time.Time, lat, long float64) time.Time {
+	next, _ := NextTransitionTime(t, lat, long)
+	if next.IsZero() {
+		// The temperature won't change any time soon, but check again in a
+		// day anyway.
+		return t.Add(24 * time.Hour)
+	}
+
+	if IsInTransition(t, lat, long) {
+		if tick := t.Add(WatchTransitionInterval); tick.Before(next) {
+			return tick
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,99 @@
+package solar
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchCurrentTemperature(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := WatchCurrentTemperature(ctx, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+
+	select {
+	case temp := <-ch:
+		expect, _ := CurrentTemperature(latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+		if temp != expect {
+			t.Errorf("expected the current temperature %.0fK, got %.0fK", expect, temp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first temperature")
+	}
+
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the channel to close")
+		}
+	}
+}
+
+func TestNextWatchTime(t *testing.T) {
+	ts := time.Unix(1636333967, 0).In(losAngeles)
+	sun := CalculateSun(ts, latitude, longitude)
+
+	midday := timeIn(t, ts, "12:00:00")
+	if next := nextWatchTime(midday, latitude, longitude); !next.Equal(sun.Sunset) {
+		t.Errorf("midday: expected to wake up at sunset %v, got %v", sun.Sunset, next)
+	}
+
+	dusk := sun.Sunset.Add(time.Second)
+	if next := nextWatchTime(dusk, latitude, longitude); !next.Equal(dusk.Add(WatchTransitionInterval)) {
+		t.Errorf("dusk: expected to wake up in a minute, got %v", next)
+	}
+
+	almostDark := sun.Dusk.Add(-time.Second)
+	if next := nextWatchTime(almostDark, latitude, longitude); !next.Equal(sun.Dusk) {
+		t.Errorf("end of dusk: expected to wake up at dusk %v, got %v", sun.Dusk, next)
+	}
+}
+
+func TestSendLatest(t *testing.T) {
+	ch := make(chan Temperature, 1)
+	sendLatest(ch, 4000)
+	sendLatest(ch, 5000)
+
+	if temp := <-ch; temp != 5000 {
+		t.Errorf("expected the latest temperature 5000K, got %.0fK", temp)
+	}
+}
+
+func TestWatchWhitepoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := WatchWhitepoint(ctx, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+
+	select {
+	case wp := <-ch:
+		temp, _ := CurrentTemperature(latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+		if r, g, b := CalculateWhitepoint(temp); wp != [3]float64{r, g, b} {
+			t.Errorf("expected the whitepoint of %.0fK, got %v", temp, wp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first whitepoint")
+	}
+
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the channel to close")
+		}
+	}
+}
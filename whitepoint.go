@@ -0,0 +1,312 @@
+package solar
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WhitepointBlend describes how the whitepoint transitions from the Planckian
+// locus, which is used for warm temperatures, to illuminant D (daylight), which
+// is used for cool temperatures.
+type WhitepointBlend struct {
+	// Low and High are the bounds of the transition. Below Low, only the
+	// Planckian locus is used, and from High, only illuminant D is used. Low
+	// is clamped to at least 2500K and High to below 25000K, where both are
+	// defined. If High is not above Low, the transition is a hard switch at
+	// Low.
+	Low, High Temperature
+	// Factor returns the weight of illuminant D within [0.0, 1.0] for the
+	// given position in the transition, which is 0.0 at High and 1.0 at Low.
+	// If nil, CosineBlend is used.
+	Factor func(pos float64) float64
+}
+
+// DefaultWhitepointBlend is the blend that CalculateWhitepoint uses, which is
+// the same as wlsunset's.
+var DefaultWhitepointBlend = WhitepointBlend{
+	Low:    2500,
+	High:   4000,
+	Factor: CosineBlend,
+}
+
+// CosineBlend is a WhitepointBlend factor that eases in and out of the
+// transition along a cosine curve.
+func CosineBlend(pos float64) float64 {
+	return (math.Cos(math.Pi*pos) + 1.0) / 2.0
+}
+
+// LinearBlend is a WhitepointBlend factor that transitions linearly.
+func LinearBlend(pos float64) float64 {
+	return 1 - pos
+}
+
+// CalculateWhitepointBlend is like CalculateWhitepoint, except the transition
+// between the Planckian locus and illuminant D is done using the given blend
+// instead of DefaultWhitepointBlend.
+func CalculateWhitepointBlend(temp Temperature, blend WhitepointBlend) (r, g, b float64) {
+	if temp == 6500 && blend.High <= 6500 {
+		return 1, 1, 1
+	}
+
+	return chromaticityToRGB(blend.chromaticity(temp))
+}
+
+// chromaticity calculates the xy chromaticity of the whitepoint for the given
+// color temperature using the blend. The temperature is clamped to [1667K,
+// 25000K].
+func (blend WhitepointBlend) chromaticity(temp Temperature) (x, y float64) {
+	low := blend.Low
+	if low < 2500 {
+		low = 2500
+	}
+	high := blend.High
+	if high >= 25000 {
+		high = 24999
+	}
+	if high < low {
+		high = low
+	}
+
+	switch {
+	case temp >= 25000:
+		x, y = illuminantD(25000)
+	case temp >= high:
+		x, y = illuminantD(float64(temp))
+	case temp >= low:
+		factor := blend.Factor
+		if factor == nil {
+			factor = CosineBlend
+		}
+
+		x1, y1 := illuminantD(float64(temp))
+		x2, y2 := planckianLocus(float64(temp))
+		weight := clamp(factor(float64((high - temp) / (high - low))))
+		x = x1*weight + x2*(1.0-weight)
+		y = y1*weight + y2*(1.0-weight)
+	case temp >= 1667:
+		x, y = planckianLocus(float64(temp))
+	default:
+		x, y = planckianLocus(1667)
+	}
+	return
+}
+
+// relativeLuminance calculates the relative luminance (the Y channel) of the
+// given RGB color using the Rec. 709 coefficients.
+func relativeLuminance(r, g, b float64) float64 {
+	// https://en.wikipedia.org/wiki/Relative_luminance
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// CalculateWhitepoint8 calculates the whitepoint for the given color
+// temperature like CalculateWhitepoint, except each channel is scaled to
+// [0, 255] and rounded to the nearest integer.
+func CalculateWhitepoint8(temp Temperature) (r, g, b uint8) {
+	rf, gf, bf := CalculateWhitepoint(temp)
+	return to8Bit(rf), to8Bit(gf), to8Bit(bf)
+}
+
+// WhitepointHex returns the whitepoint for the given color temperature as a
+// lowercase "#rrggbb" string, using the channels of CalculateWhitepoint8.
+func WhitepointHex(temp Temperature) string {
+	r, g, b := CalculateWhitepoint8(temp)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// WhitepointColor returns the whitepoint for the given color temperature as an
+// opaque color.RGBA, using the channels of CalculateWhitepoint8. Since it's
+// opaque, it is the same whether or not it's premultiplied by the alpha.
+func WhitepointColor(temp Temperature) color.RGBA {
+	r, g, b := CalculateWhitepoint8(temp)
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
+
+// to8Bit converts the given channel in [0.0, 1.0] to [0, 255], rounding to the
+// nearest integer. Values outside the range are clamped.
+func to8Bit(v float64) uint8 {
+	return uint8(math.Round(clamp(v) * 255))
+}
+
+// GammaRamp builds the gamma ramps of the given size for the red, green and
+// blue channels at the given color temperature, such as for DRM or RANDR.
+// Each entry is its index scaled linearly to [0, 65535], then scaled by the
+// channel of the whitepoint from CalculateWhitepoint. Nil ramps are returned if
+// size is not positive.
+func GammaRamp(temp Temperature, size int) (r, g, b []uint16) {
+	if size <= 0 {
+		return nil, nil, nil
+	}
+
+	rw, gw, bw := CalculateWhitepoint(temp)
+	return gammaRampChannel(rw, size), gammaRampChannel(gw, size), gammaRampChannel(bw, size)
+}
+
+// gammaRampChannel builds the gamma ramp of the given size for a single
+// channel scaled by the given whitepoint, which is clamped to [0.0, 1.0]. A
+// ramp of size 1 only has the whitepoint.
+func gammaRampChannel(whitepoint float64, size int) []uint16 {
+	whitepoint = clamp(whitepoint)
+
+	ramp := make([]uint16, size)
+	if size == 1 {
+		ramp[0] = uint16(math.Round(whitepoint * math.MaxUint16))
+		return ramp
+	}
+
+	for i := range ramp {
+		ramp[i] = uint16(math.Round(float64(i) / float64(size-1) * whitepoint * math.MaxUint16))
+	}
+
+	return ramp
+}
+
+// CalculateWhitepointBrightness calculates the whitepoint for the given color
+// temperature like CalculateWhitepoint, then scales all channels by the given
+// brightness for dimming. The brightness is clamped to [0.0, 1.0], so a
+// brightness of 1.0 is the same as CalculateWhitepoint, and 0.0 or NaN is
+// black.
+func CalculateWhitepointBrightness(temp Temperature, brightness float64) (r, g, b float64) {
+	r, g, b = CalculateWhitepoint(temp)
+	brightness = clamp(brightness)
+	return r * brightness, g * brightness, b * brightness
+}
+
+// WhitepointAtLuminance calculates the whitepoint for the given color
+// temperature like CalculateWhitepoint, except the channels are scaled so that
+// the relative luminance of the whitepoint (0.2126R + 0.7152G + 0.0722B) is the
+// given luminance. Scaling all channels by the same factor preserves the
+// chromaticity, unlike scaling each channel on its own.
+//
+// The luminance is clamped to [0.0, 1.0]. Because no channel can go over 1.0,
+// the brightest possible whitepoint for a temperature is the one returned by
+// CalculateWhitepoint; a luminance higher than what it has will return that
+// whitepoint instead.
+func WhitepointAtLuminance(temp Temperature, luminance float64) (r, g, b float64) {
+	r, g, b = CalculateWhitepoint(temp)
+
+	y := relativeLuminance(r, g, b)
+	if y <= 0 {
+		return 0, 0, 0
+	}
+
+	luminance = clamp(luminance)
+	if luminance >= y {
+		return r, g, b
+	}
+
+	scale := luminance / y
+	return r * scale, g * scale, b * scale
+}
+
+// WhitepointGradient calculates the whitepoint at the given position between
+// the lo and hi color temperatures. The position is clamped to [0.0, 1.0],
+// where 0 is the whitepoint of lo and 1 is the whitepoint of hi.
+//
+// The interpolation is done in the chromaticity space before converting to
+// RGB, which gives a more natural looking gradient than interpolating the RGB
+// values of the two whitepoints directly.
+func WhitepointGradient(lo, hi Temperature, pos float64) (r, g, b float64) {
+	switch pos = clamp(pos); pos {
+	case 0:
+		return CalculateWhitepoint(lo)
+	case 1:
+		return CalculateWhitepoint(hi)
+	}
+
+	x1, y1 := whitepointChromaticity(lo)
+	x2, y2 := whitepointChromaticity(hi)
+
+	return chromaticityToRGB(x1+(x2-x1)*pos, y1+(y2-y1)*pos)
+}
+
+// NormalizeRGB scales the given channels so that the largest one is 1.0, which
+// is how the whitepoints are normalized. If no channel is positive, then
+// (0, 0, 0) is returned instead of NaNs.
+func NormalizeRGB(r, g, b float64) (float64, float64, float64) {
+	return srgbNormalize(r, g, b)
+}
+
+// batchParallelMin is the minimum number of temperatures for
+// CalculateWhitepointBatch to spread the work across multiple goroutines.
+const batchParallelMin = 1024
+
+// CalculateWhitepointBatch calls CalculateWhitepoint for each of the given
+// temperatures and returns the RGB whitepoints in the same order. Large inputs
+// are calculated in parallel.
+func CalculateWhitepointBatch(temps []Temperature) [][3]float64 {
+	whitepoints := make([][3]float64, len(temps))
+
+	calc := func(start, end int) {
+		for i := start; i < end; i++ {
+			r, g, b := CalculateWhitepoint(temps[i])
+			whitepoints[i] = [3]float64{r, g, b}
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if len(temps) < batchParallelMin || workers < 2 {
+		calc(0, len(temps))
+		return whitepoints
+	}
+
+	chunk := (len(temps) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(temps); start += chunk {
+		end := start + chunk
+		if end > len(temps) {
+			end = len(temps)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			calc(start, end)
+			wg.Done()
+		}(start, end)
+	}
+	wg.Wait()
+
+	return whitepoints
+}
+
+// WhitepointPath samples the given number of whitepoints evenly along the
+// gradient from lo to hi, as calculated by WhitepointGradient. The first sample
+// is the whitepoint of lo and the last is hi, so the returned slice can be
+// uploaded as a 1D lookup table.
+func WhitepointPath(lo, hi Temperature, steps int) [][3]float64 {
+	if steps <= 0 {
+		return nil
+	}
+
+	path := make([][3]float64, steps)
+	if steps == 1 {
+		r, g, b := CalculateWhitepoint(lo)
+		path[0] = [3]float64{r, g, b}
+		return path
+	}
+
+	for i := range path {
+		r, g, b := WhitepointGradient(lo, hi, float64(i)/float64(steps-1))
+		path[i] = [3]float64{r, g, b}
+	}
+
+	return path
+}
+
+// CalculateColor calculates the color temperature for the given time along
+// with its whitepoint. See CalculateTemperature and CalculateWhitepoint.
+func CalculateColor(t time.Time, lat, long float64, lo, hi Temperature) (Temperature, [3]float64) {
+	temp, _ := CalculateTemperature(t, lat, long, lo, hi)
+	r, g, b := CalculateWhitepoint(temp)
+	return temp, [3]float64{r, g, b}
+}
+
+// CalculateColorAt calls CalculateColor for the time that is the given offset
+// after base. It is useful for previewing the color in the future.
+func CalculateColorAt(base time.Time, offset time.Duration, lat, long float64, lo, hi Temperature) (Temperature, [3]float64) {
+	return CalculateColor(base.Add(offset), lat, long, lo, hi)
+}
@@ -0,0 +1,319 @@
+package solar
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhitepointAtLuminance(t *testing.T) {
+	temps := []Temperature{1667, 2500, 4000, 6500, 10000}
+	luminances := []float64{0, 0.1, 0.25, 0.5}
+
+	for _, temp := range temps {
+		for _, luminance := range luminances {
+			t.Run(fmt.Sprintf("%.0fK/%.2f", temp, luminance), func(t *testing.T) {
+				r, g, b := WhitepointAtLuminance(temp, luminance)
+				if y := relativeLuminance(r, g, b); !feq(y, luminance) {
+					t.Errorf("expected luminance %f, got %f", luminance, y)
+				}
+
+				// The ratio between the channels must be the same as the
+				// full whitepoint's.
+				wr, wg, wb := CalculateWhitepoint(temp)
+				if luminance > 0 && (!feq(r/wr*wg, g) || !feq(r/wr*wb, b)) {
+					t.Errorf("chromaticity changed: (%f, %f, %f) vs (%f, %f, %f)", r, g, b, wr, wg, wb)
+				}
+			})
+		}
+	}
+
+	t.Run("too bright", func(t *testing.T) {
+		r, g, b := WhitepointAtLuminance(4000, 1)
+		if c, expect := rgb(r, g, b), rgb(CalculateWhitepoint(4000)); c != expect {
+			t.Errorf("expected %v, got %v", expect, c)
+		}
+	})
+}
+
+func TestWhitepointGradient(t *testing.T) {
+	const lo, hi Temperature = 4000, 6500
+
+	t.Run("endpoints", func(t *testing.T) {
+		if c, expect := rgb(WhitepointGradient(lo, hi, 0)), rgb(CalculateWhitepoint(lo)); c != expect {
+			t.Errorf("pos 0: expected %v, got %v", expect, c)
+		}
+		if c, expect := rgb(WhitepointGradient(lo, hi, 1)), rgb(CalculateWhitepoint(hi)); c != expect {
+			t.Errorf("pos 1: expected %v, got %v", expect, c)
+		}
+	})
+
+	t.Run("middle", func(t *testing.T) {
+		c0 := rgb(CalculateWhitepoint(lo))
+		c1 := rgb(CalculateWhitepoint(hi))
+		mid := rgb(WhitepointGradient(lo, hi, 0.5))
+
+		for i := range mid {
+			min, max := c0[i], c1[i]
+			if min > max {
+				min, max = max, min
+			}
+			if mid[i] < min-1e-5 || mid[i] > max+1e-5 {
+				t.Errorf("channel %d: %f not between %f and %f", i, mid[i], min, max)
+			}
+		}
+
+		if mid == c0 || mid == c1 {
+			t.Errorf("expected a color between %v and %v, got %v", c0, c1, mid)
+		}
+	})
+}
+
+func TestNormalizeRGB(t *testing.T) {
+	if c := rgb(NormalizeRGB(0.5, 0.25, 0.1)); c != rgb(1, 0.5, 0.2) {
+		t.Errorf("expected (1, 0.5, 0.2), got %v", c)
+	}
+
+	if c := rgb(NormalizeRGB(0, 0, 0)); c != rgb(0, 0, 0) {
+		t.Errorf("expected (0, 0, 0), got %v", c)
+	}
+}
+
+func TestCalculateWhitepointBatch(t *testing.T) {
+	for _, n := range []int{0, 10, batchParallelMin * 3} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			temps := make([]Temperature, n)
+			for i := range temps {
+				temps[i] = Temperature(1000 + i*25000/(n+1))
+			}
+
+			whitepoints := CalculateWhitepointBatch(temps)
+			if len(whitepoints) != n {
+				t.Fatalf("expected %d whitepoints, got %d", n, len(whitepoints))
+			}
+
+			for i, temp := range temps {
+				if c := rgb(CalculateWhitepoint(temp)); c != whitepoints[i] {
+					t.Fatalf("%.0fK: expected %v, got %v", temp, c, whitepoints[i])
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCalculateWhitepointBatch(b *testing.B) {
+	temps := make([]Temperature, 1000)
+	for i := range temps {
+		temps[i] = Temperature(1000 + i*25)
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			CalculateWhitepointBatch(temps)
+		}
+	})
+
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, temp := range temps {
+				CalculateWhitepoint(temp)
+			}
+		}
+	})
+}
+
+func TestWhitepointPath(t *testing.T) {
+	const lo, hi Temperature = 1667, 6500
+	const steps = 256
+
+	path := WhitepointPath(lo, hi, steps)
+	if len(path) != steps {
+		t.Fatalf("expected %d steps, got %d", steps, len(path))
+	}
+
+	if c := rgb(CalculateWhitepoint(lo)); path[0] != c {
+		t.Errorf("first: expected %v, got %v", c, path[0])
+	}
+	if c := rgb(CalculateWhitepoint(hi)); path[steps-1] != c {
+		t.Errorf("last: expected %v, got %v", c, path[steps-1])
+	}
+
+	// Adjacent steps are 1/255th of the way apart, so no channel should jump
+	// anywhere near that much.
+	for i := 1; i < steps; i++ {
+		for ch := range path[i] {
+			if d := math.Abs(path[i][ch] - path[i-1][ch]); d > 0.02 {
+				t.Errorf("step %d: channel %d jumped by %f", i, ch, d)
+			}
+		}
+	}
+
+	if path := WhitepointPath(lo, hi, 0); path != nil {
+		t.Errorf("expected no steps, got %v", path)
+	}
+}
+
+func TestCalculateColorAt(t *testing.T) {
+	base := time.Date(2021, time.November, 7, 15, 0, 0, 0, losAngeles)
+
+	for _, offset := range []time.Duration{0, time.Hour, 2*time.Hour + 30*time.Minute, 12 * time.Hour} {
+		temp, wp := CalculateColorAt(base, offset, latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+
+		expectTemp, _ := CalculateTemperature(base.Add(offset), latitude, longitude, DefaultLowTemperature, DefaultHighTemperature)
+		r, g, b := CalculateWhitepoint(expectTemp)
+
+		if temp != expectTemp {
+			t.Errorf("%v: expected %.0fK, got %.0fK", offset, expectTemp, temp)
+		}
+		if wp != [3]float64{r, g, b} {
+			t.Errorf("%v: expected whitepoint %v, got %v", offset, [3]float64{r, g, b}, wp)
+		}
+	}
+}
+
+func TestCalculateWhitepoint8(t *testing.T) {
+	for _, temp := range []Temperature{1000, 1667, 3000, 4500, 6500, 10000, 25000} {
+		r, g, b := CalculateWhitepoint8(temp)
+		rf, gf, bf := CalculateWhitepoint(temp)
+
+		for i, c := range [][2]float64{{float64(r), rf}, {float64(g), gf}, {float64(b), bf}} {
+			if diff := math.Abs(c[0] - c[1]*255); diff > 0.5 {
+				t.Errorf("%.0fK channel %d: %.0f is not %f rounded", temp, i, c[0], c[1]*255)
+			}
+		}
+	}
+
+	if r, g, b := CalculateWhitepoint8(6500); r != 255 || g != 255 || b != 255 {
+		t.Errorf("expected white at 6500K, got (%d, %d, %d)", r, g, b)
+	}
+
+	if v := to8Bit(0.999); v != 255 {
+		t.Errorf("expected 0.999 to round up to 255, got %d", v)
+	}
+	if v := to8Bit(1.5); v != 255 {
+		t.Errorf("expected 1.5 to be clamped to 255, got %d", v)
+	}
+}
+
+func TestWhitepointHex(t *testing.T) {
+	if hex := WhitepointHex(6500); hex != "#ffffff" {
+		t.Errorf("expected #ffffff at 6500K, got %s", hex)
+	}
+
+	hex := WhitepointHex(1667)
+	t.Log("1667K:", hex)
+
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil || len(hex) != 7 {
+		t.Fatalf("expected a #rrggbb string, got %q", hex)
+	}
+	if r != 0xff || math.Abs(float64(g)-0x76) > 8 || b > 8 {
+		t.Errorf("expected about #ff7600 at 1667K, got %s", hex)
+	}
+
+	if hex := WhitepointHex(1000); hex != strings.ToLower(hex) {
+		t.Errorf("expected a lowercase string, got %s", hex)
+	}
+}
+
+func TestWhitepointColor(t *testing.T) {
+	for _, temp := range []Temperature{1000, 1667, 3000, 4500, 6500, 10000} {
+		r, g, b := CalculateWhitepoint8(temp)
+		if c := WhitepointColor(temp); c != (color.RGBA{r, g, b, 255}) {
+			t.Errorf("%.0fK: expected (%d, %d, %d, 255), got %v", temp, r, g, b, c)
+		}
+	}
+}
+
+func TestGammaRamp(t *testing.T) {
+	const size = 1024
+
+	r, g, b := GammaRamp(2500, size)
+	rw, gw, bw := CalculateWhitepoint(2500)
+
+	for c, test := range []struct {
+		ramp       []uint16
+		whitepoint float64
+	}{{r, rw}, {g, gw}, {b, bw}} {
+		if len(test.ramp) != size {
+			t.Fatalf("channel %d: expected %d entries, got %d", c, size, len(test.ramp))
+		}
+		if test.ramp[0] != 0 {
+			t.Errorf("channel %d: expected the ramp to start at 0, got %d", c, test.ramp[0])
+		}
+		if end := uint16(math.Round(test.whitepoint * math.MaxUint16)); test.ramp[size-1] != end {
+			t.Errorf("channel %d: expected the ramp to end at %d, got %d", c, end, test.ramp[size-1])
+		}
+		for i := 1; i < size; i++ {
+			if test.ramp[i] < test.ramp[i-1] {
+				t.Fatalf("channel %d: ramp decreases at %d", c, i)
+			}
+		}
+	}
+
+	if v := gammaRampChannel(0.5, 256)[255]; v != math.MaxUint16/2+1 {
+		t.Errorf("expected the half ramp to end at %d, got %d", math.MaxUint16/2+1, v)
+	}
+	if v := gammaRampChannel(2, 256)[255]; v != math.MaxUint16 {
+		t.Errorf("expected whitepoints above 1 to be clamped, got %d", v)
+	}
+	if v := gammaRampChannel(1, 1); len(v) != 1 || v[0] != math.MaxUint16 {
+		t.Errorf("expected a single entry ramp to be the whitepoint, got %v", v)
+	}
+	if r, g, b := GammaRamp(6500, 0); r != nil || g != nil || b != nil {
+		t.Errorf("expected nil ramps for size 0")
+	}
+}
+
+func TestCalculateWhitepointBrightness(t *testing.T) {
+	for _, temp := range []Temperature{1667, 3000, 6500} {
+		if c := rgb(CalculateWhitepointBrightness(temp, 1)); c != rgb(CalculateWhitepoint(temp)) {
+			t.Errorf("%.0fK: expected full brightness to match CalculateWhitepoint, got %v", temp, c)
+		}
+		for _, brightness := range []float64{0, -1, math.NaN()} {
+			if c := rgb(CalculateWhitepointBrightness(temp, brightness)); c != rgb(0, 0, 0) {
+				t.Errorf("%.0fK at %v: expected black, got %v", temp, brightness, c)
+			}
+		}
+	}
+
+	if c := rgb(CalculateWhitepointBrightness(6500, 0.5)); c != rgb(0.5, 0.5, 0.5) {
+		t.Errorf("expected half brightness white, got %v", c)
+	}
+}
+
+func TestCalculateWhitepointBlend(t *testing.T) {
+	for temp := Temperature(1000); temp <= 25000; temp += 250 {
+		if c := rgb(CalculateWhitepointBlend(temp, DefaultWhitepointBlend)); c != rgb(CalculateWhitepoint(temp)) {
+			t.Errorf("%.0fK: expected the default blend to match CalculateWhitepoint, got %v", temp, c)
+		}
+	}
+
+	// At 3000K, the linear factor weighs illuminant D by 1/3 while the cosine
+	// factor weighs it by 1/4, which is slightly greener and less blue.
+	cosine := rgb(CalculateWhitepointBlend(3000, DefaultWhitepointBlend))
+	linear := rgb(CalculateWhitepointBlend(3000, WhitepointBlend{Low: 2500, High: 4000, Factor: LinearBlend}))
+	t.Log("3000K cosine:", cosine, "linear:", linear)
+
+	if linear[1] <= cosine[1] || linear[2] >= cosine[2] {
+		t.Errorf("expected the linear blend to be greener and less blue at 3000K, got %v and %v", linear, cosine)
+	}
+
+	// A blend starting from 3000K only uses the Planckian locus at 3000K.
+	x, y := planckianLocus(3000)
+	planck := rgb(chromaticityToRGB(x, y))
+	if c := rgb(CalculateWhitepointBlend(3000, WhitepointBlend{Low: 3000, High: 5000})); c != planck {
+		t.Errorf("expected only the Planckian locus at Low, got %v instead of %v", c, planck)
+	}
+
+	t.Run("0K-50000K", func(t *testing.T) {
+		// Ensure that out of range bounds never panic.
+		blend := WhitepointBlend{Low: 0, High: 100000, Factor: func(float64) float64 { return 2 }}
+		for f := Temperature(0); f < 50000; f += 7 {
+			CalculateWhitepointBlend(f, blend)
+		}
+	})
+}